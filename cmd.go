@@ -18,6 +18,11 @@ import (
 
 func main() {
 
+	// Scan job pods only scan and print their report
+	if len(os.Getenv(controller.ScanJobImageEnv)) > 0 {
+		os.Exit(controller.RunScanJob())
+	}
+
 	config, err := restclient.InClusterConfig()
 	if err != nil {
 		log.Printf("Error getting in cluster config. Fallback to native config. Error message: %s", err)
@@ -41,7 +46,24 @@ func main() {
 	}
 
 	c := controller.NewController(openshiftClient, kubeClient)
+	// Followers serve the admission webhook too and need the policies
+	go c.WatchPolicies()
+	go c.RunLeaderElection()
+	go c.RunScanWorker()
+	if os.Getenv("WATCH_BUILDS") != "false" {
+		go c.WatchBuilds()
+	}
+	go c.WatchRescans()
+	go func() {
+		log.Fatal(c.ServeRescans())
+	}()
+	if opts := controller.NewDefaultAdmissionOptions(); opts != nil {
+		go func() {
+			log.Fatal(c.ServeAdmission(opts))
+		}()
+	}
 	for true {
+		c.WaitForLeadership()
 		c.ScanImages()
 		time.Sleep(time.Hour)
 	}
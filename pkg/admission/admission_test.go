@@ -0,0 +1,114 @@
+package admission
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"testing"
+
+	"github.com/RedHatInsights/insights-goapi/common"
+
+	"github.com/RedHatInsights/insights-ocp-controller/pkg/annotations"
+	"github.com/RedHatInsights/insights-ocp-controller/pkg/policy"
+)
+
+func TestPodContainerImages(t *testing.T) {
+	data, err := ioutil.ReadFile("testdata/pod.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var review Review
+	if err := json.Unmarshal(data, &review); err != nil {
+		t.Fatal(err)
+	}
+	images, err := review.Request.ContainerImages()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(images) != 2 || images[0] != "registry.access.redhat.com/rhel7:latest" {
+		t.Fatalf("Unexpected images %v", images)
+	}
+}
+
+func TestDecide(t *testing.T) {
+	results := []ImageResult{
+		{Image: "good", Verdict: &policy.Verdict{Policy: "production", Compliant: true}},
+		{Image: "bad", Verdict: &policy.Verdict{Policy: "production", Violations: []policy.Violation{{Message: "1 critical findings, at most 0 allowed"}}}},
+	}
+
+	if response := Decide("uid", results, policy.EnforceDeny, false); response.Allowed || response.Result == nil {
+		t.Fatalf("Expected denial, got %+v", response)
+	}
+	if response := Decide("uid", results, policy.EnforceWarn, false); !response.Allowed || len(response.Warnings) != 1 {
+		t.Fatalf("Expected warning, got %+v", response)
+	}
+	if response := Decide("uid", results, policy.EnforceAllow, false); !response.Allowed || len(response.Warnings) != 0 {
+		t.Fatalf("Expected plain admission, got %+v", response)
+	}
+
+	unscanned := []ImageResult{{Image: "new"}}
+	if response := Decide("uid", unscanned, policy.EnforceDeny, false); !response.Allowed {
+		t.Fatalf("Expected unscanned image admitted when failing open, got %+v", response)
+	}
+	if response := Decide("uid", unscanned, policy.EnforceDeny, true); response.Allowed {
+		t.Fatalf("Expected unscanned image denied when failing closed, got %+v", response)
+	}
+}
+
+func TestImageVerdictUsesAdmittingNamespacePolicy(t *testing.T) {
+	policies, err := policy.Parse([]byte(`
+policies:
+- name: development
+  namespaceSelector: env=dev
+  thresholds:
+  - severity: critical
+    max: 5
+- name: production
+  namespaceSelector: env=prod
+  enforcement: deny
+  thresholds:
+  - severity: critical
+    max: 0
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	mapping := annotations.DefaultMapping()
+	reports := map[string]common.Report{
+		"SEC_A|A": {Severity: "CRITICAL", Category: "Security"},
+		"SEC_B|B": {Severity: "CRITICAL", Category: "Security"},
+	}
+
+	// The scan judged the image against the policy of the dev namespace it
+	// was pushed to
+	dev := policies.For(map[string]string{"env": "dev"})
+	pushed := dev.Evaluate(reports, mapping)
+	if !pushed.Compliant {
+		t.Fatalf("Expected the image compliant in dev, got %+v", pushed)
+	}
+	verdictJSON, _ := json.Marshal(pushed)
+	imageAnnotations := map[string]string{
+		annotations.ComplianceKey: string(verdictJSON),
+		annotations.RuleHitsKey:   annotations.RuleHitsToJSON(annotations.NewRuleHits(reports)),
+	}
+
+	prod := policies.For(map[string]string{"env": "prod"})
+	verdict := ImageVerdict(imageAnnotations, prod, mapping)
+	if verdict == nil || verdict.Compliant || verdict.Policy != "production" {
+		t.Fatalf("Expected the image to violate the production policy, got %+v", verdict)
+	}
+	results := []ImageResult{{Image: "dev/app:latest", Verdict: verdict}}
+	if response := Decide("uid", results, prod.EnforcementOf(), false); response.Allowed {
+		t.Fatalf("Expected admission into prod denied, got %+v", response)
+	}
+
+	if verdict := ImageVerdict(imageAnnotations, dev, mapping); verdict == nil || !verdict.Compliant {
+		t.Fatalf("Expected the image compliant when admitted to dev, got %+v", verdict)
+	}
+}
+
+func TestImageVerdictWithoutRuleHits(t *testing.T) {
+	imageAnnotations := map[string]string{annotations.ComplianceKey: `{"policy":"development","compliant":true}`}
+	if verdict := ImageVerdict(imageAnnotations, nil, annotations.DefaultMapping()); verdict != nil {
+		t.Fatalf("Expected no verdict without recorded rule hits, got %+v", verdict)
+	}
+}
@@ -0,0 +1,66 @@
+package admission
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/RedHatInsights/insights-ocp-controller/pkg/annotations"
+	"github.com/RedHatInsights/insights-ocp-controller/pkg/policy"
+)
+
+// ImageResult is what is known about the scan of one container image.
+// Verdict is nil when the image has not been scanned.
+type ImageResult struct {
+	Image   string
+	Verdict *policy.Verdict
+}
+
+// ImageVerdict judges the findings recorded on an image against the policy of
+// the namespace a workload is admitted to. The verdict recorded by the scan
+// used the policy of the namespace the image was pushed to, so it is never
+// reused. It returns nil when the image has no recorded rule hits.
+func ImageVerdict(imageAnnotations map[string]string, p *policy.Policy, mapping *annotations.Mapping) *policy.Verdict {
+	reports, ok := annotations.ParseRuleHits(imageAnnotations)
+	if !ok {
+		return nil
+	}
+	return p.Evaluate(reports, mapping)
+}
+
+// Decide admits or rejects workloads using images with the given results.
+// Non compliant images are handled according to enforcement; unscanned ones
+// count as non compliant when failClosed is set and are otherwise admitted.
+func Decide(uid string, results []ImageResult, enforcement policy.Enforcement, failClosed bool) *Response {
+	problems := []string{}
+	for _, result := range results {
+		switch {
+		case result.Verdict == nil && failClosed:
+			problems = append(problems, fmt.Sprintf("image %s has not been scanned by Insights", result.Image))
+		case result.Verdict != nil && !result.Verdict.Compliant:
+			problems = append(problems, fmt.Sprintf("image %s violates policy %s: %s",
+				result.Image, result.Verdict.Policy, violations(result.Verdict)))
+		}
+	}
+
+	response := &Response{UID: uid, Allowed: true}
+	if len(problems) == 0 {
+		return response
+	}
+	switch enforcement {
+	case policy.EnforceWarn:
+		response.Warnings = problems
+	case policy.EnforceDeny:
+		response.Allowed = false
+		response.Result = &Result{Code: http.StatusForbidden, Message: strings.Join(problems, "; ")}
+	}
+	return response
+}
+
+func violations(verdict *policy.Verdict) string {
+	messages := make([]string, 0, len(verdict.Violations))
+	for _, violation := range verdict.Violations {
+		messages = append(messages, violation.Message)
+	}
+	return strings.Join(messages, ", ")
+}
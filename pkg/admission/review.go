@@ -0,0 +1,96 @@
+package admission
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Review is the AdmissionReview sent to and returned by validating webhooks.
+// Only the fields the webhook uses are decoded.
+type Review struct {
+	APIVersion string    `json:"apiVersion"`
+	Kind       string    `json:"kind"`
+	Request    *Request  `json:"request,omitempty"`
+	Response   *Response `json:"response,omitempty"`
+}
+
+// GroupVersionKind identifies the kind of the object under review
+type GroupVersionKind struct {
+	Group   string `json:"group"`
+	Version string `json:"version"`
+	Kind    string `json:"kind"`
+}
+
+// Request describes the operation under review
+type Request struct {
+	UID       string           `json:"uid"`
+	Kind      GroupVersionKind `json:"kind"`
+	Namespace string           `json:"namespace,omitempty"`
+	Operation string           `json:"operation,omitempty"`
+	Object    json.RawMessage  `json:"object,omitempty"`
+}
+
+// Response is the decision of the webhook
+type Response struct {
+	UID      string   `json:"uid"`
+	Allowed  bool     `json:"allowed"`
+	Result   *Result  `json:"status,omitempty"`
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// Result explains a denial
+type Result struct {
+	Message string `json:"message,omitempty"`
+	Code    int    `json:"code,omitempty"`
+}
+
+type podSpec struct {
+	InitContainers []container `json:"initContainers"`
+	Containers     []container `json:"containers"`
+}
+
+type container struct {
+	Image string `json:"image"`
+}
+
+// ContainerImages returns the images of the containers of the Pod or
+// DeploymentConfig under review, and nothing for other kinds
+func (r *Request) ContainerImages() ([]string, error) {
+	var spec podSpec
+	switch r.Kind.Kind {
+	case "Pod":
+		var pod struct {
+			Spec podSpec `json:"spec"`
+		}
+		if err := json.Unmarshal(r.Object, &pod); err != nil {
+			return nil, fmt.Errorf("Unable to decode pod: %v", err)
+		}
+		spec = pod.Spec
+	case "DeploymentConfig":
+		var dc struct {
+			Spec struct {
+				Template *struct {
+					Spec podSpec `json:"spec"`
+				} `json:"template"`
+			} `json:"spec"`
+		}
+		if err := json.Unmarshal(r.Object, &dc); err != nil {
+			return nil, fmt.Errorf("Unable to decode deployment config: %v", err)
+		}
+		if dc.Spec.Template != nil {
+			spec = dc.Spec.Template.Spec
+		}
+	default:
+		return nil, nil
+	}
+
+	images := []string{}
+	seen := make(map[string]bool)
+	for _, c := range append(spec.InitContainers, spec.Containers...) {
+		if len(c.Image) > 0 && !seen[c.Image] {
+			seen[c.Image] = true
+			images = append(images, c.Image)
+		}
+	}
+	return images, nil
+}
@@ -0,0 +1,38 @@
+package annotations
+
+const (
+	// SecurityKey holds the security findings of the image
+	SecurityKey = "quality.images.openshift.io/vulnerability.redhatinsights"
+	// OperationsKey holds the stability, performance and availability findings
+	OperationsKey = "quality.images.openshift.io/operations.redhatinsights"
+	// ScannerVersionKey records the egg version that produced the image's results
+	ScannerVersionKey = "insights.redhat.com/scanner-version"
+	// ConfigHashKey records a hash of the configuration the image's
+	// annotations were produced with besides the egg
+	ConfigHashKey = "insights.redhat.com/config-hash"
+	// ComplianceKey records the policy verdict and the violations behind it
+	ComplianceKey = "insights.redhat.com/compliance"
+	// SkipScanKey set to "true" on a namespace or image stream opts its
+	// images out of scanning
+	SkipScanKey = "insights.redhat.com/skip-scan"
+	// RescanKey set to any value on an image or image stream tag requests a
+	// rescan; the controller removes it once the image was scanned
+	RescanKey = "insights.redhat.com/rescan"
+)
+
+// Merge returns the existing annotations with values applied on top. Keys
+// with an empty value are removed, every other key is kept.
+func Merge(existing map[string]string, values map[string]string) map[string]string {
+	merged := make(map[string]string, len(existing)+len(values))
+	for key, value := range existing {
+		merged[key] = value
+	}
+	for key, value := range values {
+		if len(value) == 0 {
+			delete(merged, key)
+		} else {
+			merged[key] = value
+		}
+	}
+	return merged
+}
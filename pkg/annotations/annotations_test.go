@@ -0,0 +1,54 @@
+package annotations
+
+import (
+	"testing"
+)
+
+func TestMergeKeepsForeignAnnotations(t *testing.T) {
+	existing := map[string]string{
+		"quality.images.openshift.io/vulnerability.othervendor": `{"name":"othervendor"}`,
+		"openshift.io/image.managed":                            "true",
+		SecurityKey:                                             `{"name":"old"}`,
+	}
+	merged := Merge(existing, map[string]string{
+		SecurityKey:   `{"name":"redhatinsights"}`,
+		OperationsKey: `{"name":"redhatinsights"}`,
+	})
+
+	if merged["quality.images.openshift.io/vulnerability.othervendor"] != `{"name":"othervendor"}` {
+		t.Fatalf("Annotation of another scanner was not kept: %v", merged)
+	}
+	if merged["openshift.io/image.managed"] != "true" {
+		t.Fatalf("Foreign annotation was not kept: %v", merged)
+	}
+	if merged[SecurityKey] != `{"name":"redhatinsights"}` {
+		t.Fatalf("Insights annotation was not replaced: %v", merged)
+	}
+	if merged[OperationsKey] != `{"name":"redhatinsights"}` {
+		t.Fatalf("Insights annotation was not added: %v", merged)
+	}
+	if existing[SecurityKey] != `{"name":"old"}` {
+		t.Fatalf("Existing annotations were modified in place")
+	}
+}
+
+func TestMergeRemovesEmptyValues(t *testing.T) {
+	merged := Merge(map[string]string{
+		ScannerVersionKey: "3.0.1-1",
+		"other":           "value",
+	}, map[string]string{ScannerVersionKey: ""})
+
+	if _, ok := merged[ScannerVersionKey]; ok {
+		t.Fatalf("Empty value did not remove the annotation: %v", merged)
+	}
+	if merged["other"] != "value" {
+		t.Fatalf("Foreign annotation was not kept: %v", merged)
+	}
+}
+
+func TestMergeWithoutExisting(t *testing.T) {
+	merged := Merge(nil, map[string]string{SecurityKey: "{}"})
+	if len(merged) != 1 || merged[SecurityKey] != "{}" {
+		t.Fatalf("Unexpected annotations %v", merged)
+	}
+}
@@ -0,0 +1,237 @@
+package annotations
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ghodss/yaml"
+
+	"github.com/RedHatInsights/insights-goapi/common"
+	"github.com/RedHatInsights/insights-goapi/openshift"
+)
+
+// RiskScoreKey holds the risk score computed from likelihood and impact
+const RiskScoreKey = "insights.redhat.com/risk-score"
+
+// Summary labels in decreasing severity, indexed by their severityIndex
+var severityLabels = []string{"critical", "high", "medium", "low"}
+
+// DefaultSeverities maps insights rule severities to summary labels
+var DefaultSeverities = map[string]string{
+	"CRITICAL": "critical",
+	"ERROR":    "high",
+	"WARN":     "medium",
+	"INFO":     "low",
+}
+
+// Family is one annotation counting the findings of some categories
+type Family struct {
+	// Key is the annotation key
+	Key string `json:"key"`
+	// Description is the human readable name shown in the console
+	Description string `json:"description"`
+	// Categories are the rule categories counted. A family without
+	// categories counts every finding no earlier family counted.
+	Categories []string `json:"categories,omitempty"`
+}
+
+// Mapping configures how findings are turned into annotations
+type Mapping struct {
+	// Severities maps rule severities to the critical, high, medium and low
+	// labels. Severities not listed keep their default label.
+	Severities map[string]string `json:"severities,omitempty"`
+	// Families are matched in order against the category of each finding
+	Families []Family `json:"families,omitempty"`
+	// RiskScore enables the risk score annotation
+	RiskScore bool `json:"riskScore,omitempty"`
+	// Details enables the per rule findings annotation
+	Details bool `json:"details,omitempty"`
+	// DetailsBudget is the size limit of the findings annotation in bytes
+	DetailsBudget int `json:"detailsBudget,omitempty"`
+}
+
+// DefaultMapping counts security findings in the vulnerability annotation and
+// everything else in the operations annotation
+func DefaultMapping() *Mapping {
+	return &Mapping{
+		Severities: DefaultSeverities,
+		Families: []Family{
+			{Key: SecurityKey, Description: "Security Insights", Categories: []string{"Security"}},
+			{Key: OperationsKey, Description: "Stability, Performance and Availability Insights"},
+		},
+		DetailsBudget: DefaultDetailsBudget,
+	}
+}
+
+// ParseMapping reads a mapping from YAML or JSON on top of the defaults
+func ParseMapping(data []byte) (*Mapping, error) {
+	mapping := &Mapping{}
+	if err := yaml.Unmarshal(data, mapping); err != nil {
+		return nil, fmt.Errorf("Unable to parse annotation mapping: %v", err)
+	}
+
+	severities := make(map[string]string, len(DefaultSeverities))
+	for severity, label := range DefaultSeverities {
+		severities[severity] = label
+	}
+	for severity, label := range mapping.Severities {
+		if SeverityIndex(label) < 0 {
+			return nil, fmt.Errorf("Severity %s maps to unknown label %q", severity, label)
+		}
+		severities[strings.ToUpper(severity)] = label
+	}
+	mapping.Severities = severities
+	if mapping.DetailsBudget <= 0 {
+		mapping.DetailsBudget = DefaultDetailsBudget
+	}
+
+	if len(mapping.Families) == 0 {
+		mapping.Families = DefaultMapping().Families
+	}
+	for _, family := range mapping.Families {
+		if len(family.Key) == 0 {
+			return nil, fmt.Errorf("Annotation family %q has no key", family.Description)
+		}
+	}
+	return mapping, nil
+}
+
+// Severity returns the summary label of a finding, or "" if it has none
+func (m *Mapping) Severity(report common.Report) string {
+	return m.Severities[strings.ToUpper(report.Severity)]
+}
+
+// Family returns the family counting a finding, or nil if none does
+func (m *Mapping) Family(report common.Report) *Family {
+	for i := range m.Families {
+		family := &m.Families[i]
+		if len(family.Categories) == 0 {
+			return family
+		}
+		for _, category := range family.Categories {
+			if strings.EqualFold(category, report.Category) {
+				return family
+			}
+		}
+	}
+	return nil
+}
+
+// SeverityIndex returns the severityIndex of a summary label, -1 if unknown
+func SeverityIndex(label string) int {
+	for i, l := range severityLabels {
+		if l == label {
+			return len(severityLabels) - 1 - i
+		}
+	}
+	return -1
+}
+
+// Annotator creates the Insights annotations of an image
+type Annotator struct {
+	mapping    *Mapping
+	refBaseURL string
+}
+
+// NewAnnotator creates an annotator for the given mapping
+func NewAnnotator(mapping *Mapping, refBaseURL string) *Annotator {
+	if mapping == nil {
+		mapping = DefaultMapping()
+	}
+	return &Annotator{mapping: mapping, refBaseURL: refBaseURL}
+}
+
+// Annotate returns the annotation values for the scan results of an image,
+// one per family plus the risk score and findings when enabled. Suppressed
+// findings only appear in the findings. Disabled annotations have empty
+// values so that they are removed from the image.
+func (a *Annotator) Annotate(scanResp *common.ScanResponse, suppressed map[string]common.Report, imageID string, compliant bool) map[string]string {
+	counts := make(map[string]map[string]int)
+	for _, family := range a.mapping.Families {
+		counts[family.Key] = make(map[string]int)
+	}
+	for _, report := range scanResp.Reports {
+		family := a.mapping.Family(report)
+		label := a.mapping.Severity(report)
+		if family == nil || len(label) == 0 {
+			continue
+		}
+		counts[family.Key][label]++
+	}
+
+	values := make(map[string]string)
+	for _, family := range a.mapping.Families {
+		annotation := &annotate.OpenshiftAnnotation{
+			Name:        "redhatinsights",
+			Description: family.Description,
+			Timestamp:   time.Now(),
+			Reference:   a.refBaseURL + "/" + imageID,
+			Compliant:   compliant,
+			Summary:     createSummary(counts[family.Key]),
+		}
+		values[family.Key] = annotation.ToJSON()
+	}
+	values[RiskScoreKey] = ""
+	if a.mapping.RiskScore {
+		values[RiskScoreKey] = strconv.Itoa(RiskScore(scanResp.Reports))
+	}
+	values[DetailsKey] = ""
+	if a.mapping.Details {
+		values[DetailsKey] = a.mapping.CreateDetails(scanResp.Reports, suppressed, a.mapping.DetailsBudget).ToJSON()
+	}
+	return values
+}
+
+// RiskScore is the highest likelihood times impact of any finding
+func RiskScore(reports map[string]common.Report) int {
+	score := 0
+	for _, report := range reports {
+		if risk := report.Likelihood * report.Impact; risk > score {
+			score = risk
+		}
+	}
+	return score
+}
+
+func createSummary(counts map[string]int) []map[string]string {
+	total := 0
+	for _, count := range counts {
+		total += count
+	}
+	if total == 0 {
+		return nil
+	}
+	summary := make([]map[string]string, 0, len(severityLabels))
+	for _, label := range severityLabels {
+		summary = append(summary, map[string]string{
+			"label":         label,
+			"data":          fmt.Sprintf("%v", counts[label]),
+			"severityIndex": fmt.Sprintf("%v", SeverityIndex(label)),
+		})
+	}
+	return summary
+}
+
+// Keys returns the annotation keys of all families, sorted
+func (m *Mapping) Keys() []string {
+	keys := make([]string, 0, len(m.Families))
+	for _, family := range m.Families {
+		keys = append(keys, family.Key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Counts returns the number of findings with each summary label
+func (m *Mapping) Counts(reports map[string]common.Report) map[string]int {
+	counts := make(map[string]int)
+	for _, report := range reports {
+		if label := m.Severity(report); len(label) > 0 {
+			counts[label]++
+		}
+	}
+	return counts
+}
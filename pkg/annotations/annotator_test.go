@@ -0,0 +1,100 @@
+package annotations
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/RedHatInsights/insights-goapi/common"
+	"github.com/RedHatInsights/insights-goapi/openshift"
+)
+
+var testResponse = &common.ScanResponse{
+	Reports: map[string]common.Report{
+		"SEC_A|A": {Severity: "CRITICAL", Category: "Security", Likelihood: 3, Impact: 4},
+		"SEC_B|B": {Severity: "WARN", Category: "Security", Likelihood: 1, Impact: 1},
+		"AVL_C|C": {Severity: "ERROR", Category: "Availability", Likelihood: 2, Impact: 2},
+		"PRF_D|D": {Severity: "INFO", Category: "Performance", Likelihood: 1, Impact: 2},
+	},
+}
+
+func summaryCounts(t *testing.T, value string) map[string]string {
+	var annotation annotate.OpenshiftAnnotation
+	if err := json.Unmarshal([]byte(value), &annotation); err != nil {
+		t.Fatal(err)
+	}
+	counts := make(map[string]string)
+	for _, entry := range annotation.Summary {
+		counts[entry["label"]] = entry["data"]
+	}
+	return counts
+}
+
+func TestDefaultMapping(t *testing.T) {
+	values := NewAnnotator(nil, "https://insights").Annotate(testResponse, nil, "SHA123456", true)
+	if len(values[SecurityKey]) == 0 || len(values[OperationsKey]) == 0 {
+		t.Fatalf("Expected security and operations annotations, got %v", values)
+	}
+	if len(values[RiskScoreKey]) != 0 || len(values[DetailsKey]) != 0 {
+		t.Fatalf("Disabled annotations should be empty, got %v", values)
+	}
+	sec := summaryCounts(t, values[SecurityKey])
+	if sec["critical"] != "1" || sec["medium"] != "1" || sec["high"] != "0" {
+		t.Fatalf("Unexpected security summary %v", sec)
+	}
+	ops := summaryCounts(t, values[OperationsKey])
+	if ops["high"] != "1" || ops["low"] != "1" || ops["critical"] != "0" {
+		t.Fatalf("Unexpected operations summary %v", ops)
+	}
+}
+
+func TestCustomMapping(t *testing.T) {
+	mapping, err := ParseMapping([]byte(`
+severities:
+  WARN: high
+families:
+- key: ` + SecurityKey + `
+  description: Security Insights
+  categories: [Security]
+- key: quality.images.openshift.io/availability.redhatinsights
+  description: Availability Insights
+  categories: [Availability]
+- key: quality.images.openshift.io/performance.redhatinsights
+  description: Performance Insights
+  categories: [Performance]
+riskScore: true
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	values := NewAnnotator(mapping, "https://insights").Annotate(testResponse, nil, "SHA123456", false)
+
+	sec := summaryCounts(t, values[SecurityKey])
+	if sec["high"] != "1" || sec["medium"] != "0" {
+		t.Fatalf("WARN was not remapped to high: %v", sec)
+	}
+	if avl := summaryCounts(t, values["quality.images.openshift.io/availability.redhatinsights"]); avl["high"] != "1" {
+		t.Fatalf("Unexpected availability summary %v", avl)
+	}
+	if prf := summaryCounts(t, values["quality.images.openshift.io/performance.redhatinsights"]); prf["low"] != "1" {
+		t.Fatalf("Unexpected performance summary %v", prf)
+	}
+	if _, ok := values[OperationsKey]; ok {
+		t.Fatalf("Operations annotation should not be created")
+	}
+	if values[RiskScoreKey] != "12" {
+		t.Fatalf("Expected risk score 12, got %s", values[RiskScoreKey])
+	}
+}
+
+func TestEmptySummary(t *testing.T) {
+	values := NewAnnotator(nil, "https://insights").Annotate(&common.ScanResponse{}, nil, "SHA123456", true)
+	if counts := summaryCounts(t, values[SecurityKey]); len(counts) != 0 {
+		t.Fatalf("Expected empty summary, got %v", counts)
+	}
+}
+
+func TestParseMappingRejectsUnknownLabel(t *testing.T) {
+	if _, err := ParseMapping([]byte("severities:\n  WARN: urgent\n")); err == nil {
+		t.Fatalf("Expected unknown label to be rejected")
+	}
+}
@@ -0,0 +1,118 @@
+package annotations
+
+import (
+	"encoding/json"
+	"sort"
+
+	"github.com/RedHatInsights/insights-goapi/common"
+)
+
+const (
+	// DetailsKey holds the per rule findings of the image
+	DetailsKey = "insights.redhat.com/findings"
+	// DefaultDetailsBudget keeps the details well within the 256kB Kubernetes
+	// allows for all annotations of an object together
+	DefaultDetailsBudget = 64 * 1024
+)
+
+// Finding describes one rule hit
+type Finding struct {
+	Rule       string `json:"rule"`
+	Title      string `json:"title,omitempty"`
+	Severity   string `json:"severity"`
+	Category   string `json:"category,omitempty"`
+	Resolution string `json:"resolution,omitempty"`
+	Reference  string `json:"reference,omitempty"`
+}
+
+// Details lists the findings of an image, most severe first, and the
+// findings suppressed as accepted risk. Omitted counts the findings of either
+// kind that did not fit in the size budget.
+type Details struct {
+	Findings   []Finding `json:"findings"`
+	Suppressed []Finding `json:"suppressed,omitempty"`
+	Omitted    int       `json:"omitted,omitempty"`
+}
+
+// CreateDetails returns the findings of reports and then the suppressed ones,
+// each most severe first, dropping the least severe ones until the JSON
+// encoding fits in budget bytes
+func (m *Mapping) CreateDetails(reports map[string]common.Report, suppressed map[string]common.Report, budget int) *Details {
+	details := &Details{Findings: []Finding{}}
+	// Room for the envelope and the largest possible omitted count
+	size := len(`{"findings":[],"omitted":}`) + 10
+	if len(suppressed) > 0 {
+		size += len(`"suppressed":[],`)
+	}
+	details.Findings, size = m.fit(reports, size, budget, &details.Omitted)
+	details.Suppressed, size = m.fit(suppressed, size, budget, &details.Omitted)
+	return details
+}
+
+// fit returns the findings of reports, most severe first, that fit in the
+// budget after size bytes, counting the others in omitted
+func (m *Mapping) fit(reports map[string]common.Report, size int, budget int, omitted *int) ([]Finding, int) {
+	findings := make([]Finding, 0, len(reports))
+	for key, report := range reports {
+		findings = append(findings, Finding{
+			Rule:       key,
+			Title:      plain(report.Title),
+			Severity:   m.Severity(report),
+			Category:   report.Category,
+			Resolution: plain(report.Resolution),
+			Reference:  plain(report.Reference),
+		})
+	}
+	sort.Sort(bySeverity(findings))
+
+	for i, finding := range findings {
+		encoded, err := json.Marshal(finding)
+		if err != nil || size+len(encoded)+1 > budget {
+			*omitted += len(findings) - i
+			return findings[:i], size
+		}
+		size += len(encoded) + 1
+	}
+	return findings, size
+}
+
+// Worst returns the n most severe findings of reports, without resolution
+// and reference so that they stay compact
+func (m *Mapping) Worst(reports map[string]common.Report, n int) []Finding {
+	findings := m.CreateDetails(reports, nil, DefaultDetailsBudget).Findings
+	if len(findings) > n {
+		findings = findings[:n]
+	}
+	for i := range findings {
+		findings[i].Resolution = ""
+		findings[i].Reference = ""
+	}
+	return findings
+}
+
+// ToJSON - return json version of the details
+func (d *Details) ToJSON() string {
+	str, err := json.Marshal(d)
+	if err != nil {
+		return "{}"
+	}
+	return string(str)
+}
+
+func plain(content *common.Content) string {
+	if content == nil {
+		return ""
+	}
+	return content.Plain
+}
+
+type bySeverity []Finding
+
+func (f bySeverity) Len() int      { return len(f) }
+func (f bySeverity) Swap(i, j int) { f[i], f[j] = f[j], f[i] }
+func (f bySeverity) Less(i, j int) bool {
+	if si, sj := SeverityIndex(f[i].Severity), SeverityIndex(f[j].Severity); si != sj {
+		return si > sj
+	}
+	return f[i].Rule < f[j].Rule
+}
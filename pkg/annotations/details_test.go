@@ -0,0 +1,86 @@
+package annotations
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/RedHatInsights/insights-goapi/common"
+)
+
+func detailedReports() map[string]common.Report {
+	return map[string]common.Report{
+		"LOW_RULE|LOW": {
+			Severity:   "INFO",
+			Category:   "Performance",
+			Title:      &common.Content{Plain: "Low finding"},
+			Resolution: &common.Content{Plain: strings.Repeat("r", 200)},
+		},
+		"CRIT_RULE|CRIT": {
+			Severity:   "CRITICAL",
+			Category:   "Security",
+			Title:      &common.Content{Plain: "Critical finding", Html: "<p>Critical finding</p>"},
+			Resolution: &common.Content{Plain: "Update the package"},
+			Reference:  &common.Content{Plain: "https://access.redhat.com/solutions/1"},
+		},
+		"HIGH_RULE|HIGH": {Severity: "ERROR", Category: "Availability"},
+	}
+}
+
+func TestDetailsOrderedBySeverity(t *testing.T) {
+	details := DefaultMapping().CreateDetails(detailedReports(), nil, DefaultDetailsBudget)
+	if details.Omitted != 0 || len(details.Findings) != 3 {
+		t.Fatalf("Unexpected details %+v", details)
+	}
+	first := details.Findings[0]
+	if first.Rule != "CRIT_RULE|CRIT" || first.Severity != "critical" || first.Title != "Critical finding" ||
+		first.Resolution != "Update the package" || first.Reference != "https://access.redhat.com/solutions/1" {
+		t.Fatalf("Unexpected first finding %+v", first)
+	}
+	if details.Findings[1].Rule != "HIGH_RULE|HIGH" || details.Findings[2].Rule != "LOW_RULE|LOW" {
+		t.Fatalf("Findings not ordered by severity %+v", details.Findings)
+	}
+}
+
+func TestDetailsListSuppressed(t *testing.T) {
+	reports := detailedReports()
+	suppressed := map[string]common.Report{"CRIT_RULE|CRIT": reports["CRIT_RULE|CRIT"]}
+	delete(reports, "CRIT_RULE|CRIT")
+
+	details := DefaultMapping().CreateDetails(reports, suppressed, DefaultDetailsBudget)
+	if len(details.Findings) != 2 || details.Findings[0].Rule != "HIGH_RULE|HIGH" {
+		t.Fatalf("Unexpected findings %+v", details.Findings)
+	}
+	if len(details.Suppressed) != 1 || details.Suppressed[0].Rule != "CRIT_RULE|CRIT" {
+		t.Fatalf("Unexpected suppressed findings %+v", details.Suppressed)
+	}
+}
+
+func TestDetailsTruncatedToBudget(t *testing.T) {
+	budget := 300
+	details := DefaultMapping().CreateDetails(detailedReports(), nil, budget)
+	if details.Omitted != 1 || len(details.Findings) != 2 {
+		t.Fatalf("Expected the low finding to be omitted, got %+v", details)
+	}
+	if value := details.ToJSON(); len(value) > budget {
+		t.Fatalf("Details of %d bytes exceed budget of %d", len(value), budget)
+	}
+
+	var decoded Details
+	if err := json.Unmarshal([]byte(details.ToJSON()), &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.Omitted != 1 {
+		t.Fatalf("Omitted count not recorded: %s", details.ToJSON())
+	}
+}
+
+func TestWorstFindings(t *testing.T) {
+	worst := DefaultMapping().Worst(detailedReports(), 2)
+	if len(worst) != 2 || worst[0].Rule != "CRIT_RULE|CRIT" || worst[1].Rule != "HIGH_RULE|HIGH" {
+		t.Fatalf("Unexpected worst findings %+v", worst)
+	}
+	if worst[0].Resolution != "" || worst[0].Reference != "" || worst[0].Title != "Critical finding" {
+		t.Fatalf("Worst findings should only keep rule, title, severity and category: %+v", worst[0])
+	}
+}
@@ -0,0 +1,103 @@
+package annotations
+
+import (
+	"encoding/json"
+	"sort"
+	"time"
+
+	"github.com/RedHatInsights/insights-goapi/common"
+)
+
+// HistoryKey holds the rule hits of the last scans of the image
+const HistoryKey = "insights.redhat.com/scan-history"
+
+// DefaultHistoryLength is the number of scans kept in the history
+const DefaultHistoryLength = 5
+
+// HistoryEntry records the rules hit by one scan
+type HistoryEntry struct {
+	Scanned        time.Time `json:"scanned"`
+	ScannerVersion string    `json:"scannerVersion,omitempty"`
+	Rules          []string  `json:"rules"`
+}
+
+// History lists the scans of an image, oldest first
+type History []HistoryEntry
+
+// Diff lists the rule hits that appeared and disappeared between two scans
+type Diff struct {
+	Added    []string `json:"added"`
+	Resolved []string `json:"resolved"`
+}
+
+// NewHistoryEntry creates the history entry of a scan
+func NewHistoryEntry(reports map[string]common.Report, scannerVersion string, scanned time.Time) HistoryEntry {
+	rules := make([]string, 0, len(reports))
+	for rule := range reports {
+		rules = append(rules, rule)
+	}
+	sort.Strings(rules)
+	return HistoryEntry{Scanned: scanned, ScannerVersion: scannerVersion, Rules: rules}
+}
+
+// ParseHistory reads the history stored in the image annotations. An image
+// never scanned has an empty history.
+func ParseHistory(imageAnnotations map[string]string) History {
+	var history History
+	if value, ok := imageAnnotations[HistoryKey]; ok {
+		json.Unmarshal([]byte(value), &history)
+	}
+	return history
+}
+
+// Append returns the history with entry added, keeping at most max entries.
+// The diff is against the previous scan and nil for the first one.
+func (h History) Append(entry HistoryEntry, max int) (History, *Diff) {
+	var diff *Diff
+	if len(h) > 0 {
+		diff = DiffRules(h[len(h)-1].Rules, entry.Rules)
+	}
+	history := append(append(History{}, h...), entry)
+	if max > 0 && len(history) > max {
+		history = history[len(history)-max:]
+	}
+	return history, diff
+}
+
+// DiffRules compares the rules hit by two scans
+func DiffRules(previous []string, current []string) *Diff {
+	diff := &Diff{Added: []string{}, Resolved: []string{}}
+	before := make(map[string]bool, len(previous))
+	for _, rule := range previous {
+		before[rule] = true
+	}
+	after := make(map[string]bool, len(current))
+	for _, rule := range current {
+		after[rule] = true
+		if !before[rule] {
+			diff.Added = append(diff.Added, rule)
+		}
+	}
+	for _, rule := range previous {
+		if !after[rule] {
+			diff.Resolved = append(diff.Resolved, rule)
+		}
+	}
+	sort.Strings(diff.Added)
+	sort.Strings(diff.Resolved)
+	return diff
+}
+
+// Empty reports whether nothing changed between the scans
+func (d *Diff) Empty() bool {
+	return d == nil || len(d.Added) == 0 && len(d.Resolved) == 0
+}
+
+// ToJSON - return json version of the history
+func (h History) ToJSON() string {
+	str, err := json.Marshal(h)
+	if err != nil {
+		return "[]"
+	}
+	return string(str)
+}
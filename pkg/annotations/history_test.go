@@ -0,0 +1,39 @@
+package annotations
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/RedHatInsights/insights-goapi/common"
+)
+
+func TestHistoryDiff(t *testing.T) {
+	now := time.Now()
+	history := ParseHistory(nil)
+
+	history, diff := history.Append(NewHistoryEntry(map[string]common.Report{
+		"A|A": {}, "B|B": {},
+	}, "3.0.8-1", now), 2)
+	if diff != nil {
+		t.Fatalf("Expected no diff for the first scan, got %+v", diff)
+	}
+
+	history = ParseHistory(map[string]string{HistoryKey: history.ToJSON()})
+	history, diff = history.Append(NewHistoryEntry(map[string]common.Report{
+		"B|B": {}, "C|C": {},
+	}, "3.0.8-1", now), 2)
+	if !reflect.DeepEqual(diff, &Diff{Added: []string{"C|C"}, Resolved: []string{"A|A"}}) {
+		t.Fatalf("Unexpected diff %+v", diff)
+	}
+
+	history, diff = history.Append(NewHistoryEntry(map[string]common.Report{
+		"B|B": {}, "C|C": {},
+	}, "3.0.9-1", now), 2)
+	if !diff.Empty() {
+		t.Fatalf("Expected empty diff, got %+v", diff)
+	}
+	if len(history) != 2 || history[0].Rules[0] != "B|B" {
+		t.Fatalf("Expected the oldest entry to be dropped, got %+v", history)
+	}
+}
@@ -0,0 +1,50 @@
+package annotations
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+)
+
+const (
+	// PromoteKey on an image stream opts it in to promotion. Its value lists
+	// source=target tag pairs separated by commas, e.g. "latest=approved".
+	PromoteKey = "insights.redhat.com/promote"
+	// PromotionKey on a promoted tag records the last promotion
+	PromotionKey = "insights.redhat.com/promotion"
+)
+
+// Promotion records who moved a tag to an image and why
+type Promotion struct {
+	Image      string    `json:"image"`
+	From       string    `json:"from"`
+	PromotedBy string    `json:"promotedBy"`
+	Policy     string    `json:"policy,omitempty"`
+	Reason     string    `json:"reason"`
+	Promoted   time.Time `json:"promoted"`
+}
+
+// ParsePromotions reads the source to target tag pairs of a PromoteKey value
+func ParsePromotions(value string) map[string]string {
+	promotions := make(map[string]string)
+	for _, pair := range strings.Split(value, ",") {
+		parts := strings.SplitN(strings.TrimSpace(pair), "=", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		source, target := strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1])
+		if len(source) > 0 && len(target) > 0 && source != target {
+			promotions[source] = target
+		}
+	}
+	return promotions
+}
+
+// ToJSON - return json version of the promotion
+func (p *Promotion) ToJSON() string {
+	str, err := json.Marshal(p)
+	if err != nil {
+		return "{}"
+	}
+	return string(str)
+}
@@ -0,0 +1,14 @@
+package annotations
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParsePromotions(t *testing.T) {
+	promotions := ParsePromotions("latest=approved, candidate = release,broken,self=self,=empty")
+	expected := map[string]string{"latest": "approved", "candidate": "release"}
+	if !reflect.DeepEqual(promotions, expected) {
+		t.Fatalf("Expected %v, got %v", expected, promotions)
+	}
+}
@@ -0,0 +1,41 @@
+package annotations
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// RebuildKey on a build config records the last rebuild the controller
+// triggered for it
+const RebuildKey = "insights.redhat.com/rebuild"
+
+// Rebuild records why a build config was rebuilt
+type Rebuild struct {
+	Triggered time.Time `json:"triggered"`
+	Build     string    `json:"build"`
+	Image     string    `json:"image"`
+	Base      string    `json:"base"`
+	Fixed     []string  `json:"fixed"`
+}
+
+// ParseRebuild reads the last rebuild stored in the build config annotations
+func ParseRebuild(buildConfigAnnotations map[string]string) *Rebuild {
+	value, ok := buildConfigAnnotations[RebuildKey]
+	if !ok {
+		return nil
+	}
+	var rebuild Rebuild
+	if err := json.Unmarshal([]byte(value), &rebuild); err != nil {
+		return nil
+	}
+	return &rebuild
+}
+
+// ToJSON - return json version of the rebuild
+func (r *Rebuild) ToJSON() string {
+	str, err := json.Marshal(r)
+	if err != nil {
+		return "{}"
+	}
+	return string(str)
+}
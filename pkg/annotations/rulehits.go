@@ -0,0 +1,59 @@
+package annotations
+
+import (
+	"encoding/json"
+	"sort"
+
+	"github.com/RedHatInsights/insights-goapi/common"
+)
+
+// RuleHitsKey holds the active rule hits of the last scan of the image, enough
+// to judge them against the policy of any namespace the image is used in
+const RuleHitsKey = "insights.redhat.com/rule-hits"
+
+// RuleHit is one active rule hit of a scan with its unmapped severity
+type RuleHit struct {
+	Rule     string `json:"rule"`
+	Severity string `json:"severity"`
+	Category string `json:"category,omitempty"`
+}
+
+// NewRuleHits records the active rule hits of a scan
+func NewRuleHits(reports map[string]common.Report) []RuleHit {
+	hits := make([]RuleHit, 0, len(reports))
+	for rule, report := range reports {
+		hits = append(hits, RuleHit{Rule: rule, Severity: report.Severity, Category: report.Category})
+	}
+	sort.Sort(byRule(hits))
+	return hits
+}
+
+// RuleHitsToJSON serializes rule hits for the rule hits annotation
+func RuleHitsToJSON(hits []RuleHit) string {
+	value, _ := json.Marshal(hits)
+	return string(value)
+}
+
+// ParseRuleHits reads the rule hits stored in the image annotations back into
+// reports. It returns false when the image has no valid rule hits annotation.
+func ParseRuleHits(imageAnnotations map[string]string) (map[string]common.Report, bool) {
+	value, ok := imageAnnotations[RuleHitsKey]
+	if !ok {
+		return nil, false
+	}
+	var hits []RuleHit
+	if err := json.Unmarshal([]byte(value), &hits); err != nil {
+		return nil, false
+	}
+	reports := make(map[string]common.Report, len(hits))
+	for _, hit := range hits {
+		reports[hit.Rule] = common.Report{Severity: hit.Severity, Category: hit.Category}
+	}
+	return reports, true
+}
+
+type byRule []RuleHit
+
+func (h byRule) Len() int           { return len(h) }
+func (h byRule) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h byRule) Less(i, j int) bool { return h[i].Rule < h[j].Rule }
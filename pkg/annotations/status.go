@@ -0,0 +1,69 @@
+package annotations
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// StatusKey holds the scan lifecycle status of the image
+const StatusKey = "insights.redhat.com/scan-status"
+
+// Phase is a stage of the scan lifecycle
+type Phase string
+
+const (
+	// PhasePending means the image was selected for scanning
+	PhasePending Phase = "Pending"
+	// PhaseScanning means the image is being scanned
+	PhaseScanning Phase = "Scanning"
+	// PhaseSucceeded means the last attempt produced results
+	PhaseSucceeded Phase = "Succeeded"
+	// PhaseFailed means the last attempt did not produce results
+	PhaseFailed Phase = "Failed"
+)
+
+// Status records how the last scan of an image went. Attempts counts the
+// attempts since the last successful scan, including the current one.
+type Status struct {
+	Phase          Phase     `json:"phase"`
+	Reason         string    `json:"reason,omitempty"`
+	Message        string    `json:"message,omitempty"`
+	LastAttempt    time.Time `json:"lastAttempt"`
+	Attempts       int       `json:"attempts"`
+	ScannerVersion string    `json:"scannerVersion,omitempty"`
+	// Diff lists the findings added and resolved since the previous scan
+	Diff *Diff `json:"diff,omitempty"`
+}
+
+// ParseStatus reads the status stored in the image annotations. An image
+// never scanned has an empty status.
+func ParseStatus(imageAnnotations map[string]string) *Status {
+	status := &Status{}
+	if value, ok := imageAnnotations[StatusKey]; ok {
+		json.Unmarshal([]byte(value), status)
+	}
+	return status
+}
+
+// NextAttempt returns the pending status of a new scan attempt
+func (s *Status) NextAttempt(scannerVersion string, now time.Time) *Status {
+	attempts := s.Attempts + 1
+	if s.Phase == PhaseSucceeded {
+		attempts = 1
+	}
+	return &Status{
+		Phase:          PhasePending,
+		LastAttempt:    now,
+		Attempts:       attempts,
+		ScannerVersion: scannerVersion,
+	}
+}
+
+// ToJSON - return json version of the status
+func (s *Status) ToJSON() string {
+	str, err := json.Marshal(s)
+	if err != nil {
+		return "{}"
+	}
+	return string(str)
+}
@@ -0,0 +1,28 @@
+package annotations
+
+import (
+	"testing"
+	"time"
+)
+
+func TestStatusAttempts(t *testing.T) {
+	now := time.Now()
+	status := ParseStatus(nil)
+	if status.Phase != "" || status.Attempts != 0 {
+		t.Fatalf("Expected empty status for a new image, got %+v", status)
+	}
+
+	status = status.NextAttempt("3.0.8-1", now)
+	status.Phase = PhaseFailed
+	status.Reason = "ImageNotPresent"
+	status = ParseStatus(map[string]string{StatusKey: status.ToJSON()}).NextAttempt("3.0.8-1", now)
+	if status.Phase != PhasePending || status.Attempts != 2 || status.Reason != "" {
+		t.Fatalf("Expected second pending attempt, got %+v", status)
+	}
+
+	status.Phase = PhaseSucceeded
+	status = ParseStatus(map[string]string{StatusKey: status.ToJSON()}).NextAttempt("3.0.9-1", now)
+	if status.Attempts != 1 || status.ScannerVersion != "3.0.9-1" {
+		t.Fatalf("Expected attempts to restart after success, got %+v", status)
+	}
+}
@@ -0,0 +1,75 @@
+package annotations
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// SummaryKey holds the compact scan summary put on image stream tags and on
+// the workloads running an image, keyed by image digest
+const SummaryKey = "insights.redhat.com/scan-summary"
+
+// ImageSummary is the compact scan result of one image
+type ImageSummary struct {
+	Critical  int       `json:"critical"`
+	High      int       `json:"high"`
+	Medium    int       `json:"medium"`
+	Low       int       `json:"low"`
+	Compliant bool      `json:"compliant"`
+	Policy    string    `json:"policy,omitempty"`
+	Scanned   time.Time `json:"scanned"`
+	Reference string    `json:"reference,omitempty"`
+	// Worst lists the most severe findings, without their resolution
+	Worst []Finding `json:"worst,omitempty"`
+}
+
+// SummaryWorstFindings is the number of findings kept in a summary
+const SummaryWorstFindings = 3
+
+// NewImageSummary creates a summary from the counts of each summary label
+// and the most severe findings
+func NewImageSummary(counts map[string]int, worst []Finding, compliant bool, policy string, reference string) *ImageSummary {
+	return &ImageSummary{
+		Critical:  counts["critical"],
+		High:      counts["high"],
+		Medium:    counts["medium"],
+		Low:       counts["low"],
+		Compliant: compliant,
+		Policy:    policy,
+		Scanned:   time.Now(),
+		Reference: reference,
+		Worst:     worst,
+	}
+}
+
+// ParseSummaries reads the summaries stored in an annotation value
+func ParseSummaries(value string) map[string]*ImageSummary {
+	summaries := make(map[string]*ImageSummary)
+	if len(value) > 0 {
+		json.Unmarshal([]byte(value), &summaries)
+	}
+	return summaries
+}
+
+// MergeSummary sets the summary of image in the existing annotation value.
+// Summaries of images not in keep are dropped, so that objects which stopped
+// using an image lose its summary.
+func MergeSummary(existing string, keep []string, image string, summary *ImageSummary) string {
+	summaries := ParseSummaries(existing)
+	kept := make(map[string]bool, len(keep))
+	for _, digest := range keep {
+		kept[digest] = true
+	}
+	for digest := range summaries {
+		if !kept[digest] {
+			delete(summaries, digest)
+		}
+	}
+	summaries[image] = summary
+
+	str, err := json.Marshal(summaries)
+	if err != nil {
+		return existing
+	}
+	return string(str)
+}
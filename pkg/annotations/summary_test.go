@@ -0,0 +1,24 @@
+package annotations
+
+import (
+	"testing"
+)
+
+func TestMergeSummary(t *testing.T) {
+	old := NewImageSummary(map[string]int{"critical": 2}, nil, false, "production", "")
+	value := MergeSummary("", []string{"sha256:old"}, "sha256:old", old)
+
+	current := NewImageSummary(map[string]int{"high": 1, "low": 3}, nil, true, "production", "")
+	value = MergeSummary(value, []string{"sha256:old", "sha256:new"}, "sha256:new", current)
+	summaries := ParseSummaries(value)
+	if len(summaries) != 2 || summaries["sha256:old"].Critical != 2 || summaries["sha256:new"].Low != 3 {
+		t.Fatalf("Unexpected summaries %s", value)
+	}
+
+	// The old image is no longer used
+	value = MergeSummary(value, []string{"sha256:new"}, "sha256:new", current)
+	summaries = ParseSummaries(value)
+	if len(summaries) != 1 || !summaries["sha256:new"].Compliant {
+		t.Fatalf("Summary of unused image was not dropped: %s", value)
+	}
+}
@@ -0,0 +1,44 @@
+package annotations
+
+import (
+	"log"
+)
+
+// Object gives access to the annotations of one stored object
+type Object interface {
+	// Get reads the object and returns its current annotations
+	Get() (map[string]string, error)
+	// Update stores annotations on the object last read by Get. It fails
+	// with a conflict when the object was modified since.
+	Update(annotations map[string]string) error
+}
+
+// Update applies values to the annotations of object with Merge, so that
+// annotations owned by anyone else are kept. The object is read again and
+// the update retried whenever isConflict reports a concurrent modification,
+// at most attempts times.
+func Update(name string, object Object, values map[string]string, isConflict func(error) bool, attempts int) error {
+	return Retry(name, isConflict, attempts, func() error {
+		existing, err := object.Get()
+		if err != nil {
+			return err
+		}
+		return object.Update(Merge(existing, values))
+	})
+}
+
+// Retry runs a read and update of the named object again whenever
+// isConflict reports that the object was modified concurrently, at most
+// attempts times
+func Retry(name string, isConflict func(error) bool, attempts int, update func() error) error {
+	for attempt := 1; ; attempt++ {
+		err := update()
+		if err == nil {
+			return nil
+		}
+		if !isConflict(err) || attempt >= attempts {
+			return err
+		}
+		log.Printf("The %s changed while annotating, retrying", name)
+	}
+}
@@ -0,0 +1,101 @@
+package annotations
+
+import (
+	"errors"
+	"testing"
+)
+
+var errConflict = errors.New("the object has been modified")
+
+func isConflict(err error) bool {
+	return err == errConflict
+}
+
+// storedObject behaves like an object in the API server: updates based on an
+// outdated read fail with a conflict
+type storedObject struct {
+	annotations map[string]string
+	version     int
+	read        int
+	updates     int
+	// beforeUpdate runs before each update as a concurrent writer would
+	beforeUpdate func(o *storedObject)
+}
+
+func (o *storedObject) Get() (map[string]string, error) {
+	o.read = o.version
+	copied := make(map[string]string, len(o.annotations))
+	for key, value := range o.annotations {
+		copied[key] = value
+	}
+	return copied, nil
+}
+
+func (o *storedObject) Update(annotations map[string]string) error {
+	o.updates++
+	if o.beforeUpdate != nil {
+		o.beforeUpdate(o)
+	}
+	if o.read != o.version {
+		return errConflict
+	}
+	o.annotations = annotations
+	o.version++
+	return nil
+}
+
+func TestUpdateKeepsForeignAnnotationsAcrossConflicts(t *testing.T) {
+	object := &storedObject{annotations: map[string]string{
+		"quality.images.openshift.io/vulnerability.othervendor": `{"name":"othervendor"}`,
+		SecurityKey: `{"name":"old"}`,
+	}}
+	// Another scanner annotates the image while the first update is written
+	object.beforeUpdate = func(o *storedObject) {
+		if o.updates == 1 {
+			o.annotations["quality.images.openshift.io/license.othervendor"] = `{"name":"license"}`
+			o.version++
+		}
+	}
+
+	values := NewAnnotator(nil, "https://insights").Annotate(testResponse, nil, "SHA123456", true)
+	values[ScannerVersionKey] = "3.0.1-1"
+	if err := Update("image SHA123456", object, values, isConflict, 5); err != nil {
+		t.Fatal(err)
+	}
+
+	if object.updates != 2 {
+		t.Fatalf("Expected the conflicting update retried once, got %d updates", object.updates)
+	}
+	if object.annotations["quality.images.openshift.io/vulnerability.othervendor"] != `{"name":"othervendor"}` {
+		t.Fatalf("Annotation of another scanner was not kept: %v", object.annotations)
+	}
+	if object.annotations["quality.images.openshift.io/license.othervendor"] != `{"name":"license"}` {
+		t.Fatalf("Concurrently added annotation was lost: %v", object.annotations)
+	}
+	if object.annotations[SecurityKey] != values[SecurityKey] || object.annotations[ScannerVersionKey] != "3.0.1-1" {
+		t.Fatalf("Scan annotations were not stored: %v", object.annotations)
+	}
+}
+
+func TestUpdateGivesUpAfterAttempts(t *testing.T) {
+	object := &storedObject{beforeUpdate: func(o *storedObject) { o.version++ }}
+	err := Update("image SHA123456", object, map[string]string{SecurityKey: "{}"}, isConflict, 3)
+	if err != errConflict {
+		t.Fatalf("Expected the conflict returned, got %v", err)
+	}
+	if object.updates != 3 {
+		t.Fatalf("Expected 3 attempts, got %d", object.updates)
+	}
+}
+
+func TestRetryStopsOnOtherErrors(t *testing.T) {
+	failed := errors.New("forbidden")
+	calls := 0
+	err := Retry("image SHA123456", isConflict, 5, func() error {
+		calls++
+		return failed
+	})
+	if err != failed || calls != 1 {
+		t.Fatalf("Expected one attempt returning the error, got %d calls and %v", calls, err)
+	}
+}
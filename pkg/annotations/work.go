@@ -0,0 +1,46 @@
+package annotations
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// WorkKey holds a scan the leader handed out to the scan workers
+const WorkKey = "insights.redhat.com/scan-work"
+
+// Work is a scan waiting for, or claimed by, a worker. Lower priorities are
+// scanned first.
+type Work struct {
+	Priority  int       `json:"priority"`
+	Requested time.Time `json:"requested"`
+	ClaimedBy string    `json:"claimedBy,omitempty"`
+	Claimed   time.Time `json:"claimed,omitempty"`
+}
+
+// ParseWork reads the work stored in the image annotations, nil without any
+func ParseWork(imageAnnotations map[string]string) *Work {
+	value, ok := imageAnnotations[WorkKey]
+	if !ok {
+		return nil
+	}
+	var work Work
+	if err := json.Unmarshal([]byte(value), &work); err != nil {
+		return nil
+	}
+	return &work
+}
+
+// Claimable reports whether a worker may claim the work: it is unclaimed or
+// its claim is older than timeout, so that work of a lost worker is redone
+func (w *Work) Claimable(now time.Time, timeout time.Duration) bool {
+	return len(w.ClaimedBy) == 0 || now.Sub(w.Claimed) > timeout
+}
+
+// ToJSON - return json version of the work
+func (w *Work) ToJSON() string {
+	str, err := json.Marshal(w)
+	if err != nil {
+		return "{}"
+	}
+	return string(str)
+}
@@ -0,0 +1,26 @@
+package annotations
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWorkClaims(t *testing.T) {
+	now := time.Now()
+	if ParseWork(nil) != nil {
+		t.Fatal("Expected no work on an image without the annotation")
+	}
+	work := &Work{Priority: 3, Requested: now}
+	work = ParseWork(map[string]string{WorkKey: work.ToJSON()})
+	if work == nil || work.Priority != 3 || !work.Claimable(now, time.Hour) {
+		t.Fatalf("Expected claimable work, got %+v", work)
+	}
+	work.ClaimedBy = "worker-1"
+	work.Claimed = now
+	if work.Claimable(now.Add(time.Minute), time.Hour) {
+		t.Fatal("Expected claimed work not to be claimable")
+	}
+	if !work.Claimable(now.Add(2*time.Hour), time.Hour) {
+		t.Fatal("Expected an expired claim to be claimable")
+	}
+}
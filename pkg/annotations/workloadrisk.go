@@ -0,0 +1,85 @@
+package annotations
+
+import (
+	"encoding/json"
+	"strconv"
+
+	"github.com/RedHatInsights/insights-goapi/openshift"
+)
+
+// WorkloadRiskKey holds the workload aware risk score of the image
+const WorkloadRiskKey = "insights.redhat.com/workload-risk"
+
+// maxCountedPods caps how much running more pods adds to the risk
+const maxCountedPods = 10
+
+// findingWeights rates one finding of each summary label
+var findingWeights = map[string]int{
+	"critical": 40,
+	"high":     20,
+	"medium":   5,
+	"low":      1,
+}
+
+// WorkloadRisk combines the findings of an image with how it is used. Score
+// grows with the findings and is multiplied by the running pods, by exposure
+// through routes and by privileged pods.
+type WorkloadRisk struct {
+	Score       int            `json:"score"`
+	RunningPods int            `json:"runningPods"`
+	Routes      int            `json:"routes"`
+	Privileged  bool           `json:"privileged"`
+	Findings    map[string]int `json:"findings,omitempty"`
+}
+
+// NewWorkloadRisk scores an image with the given finding counts per summary
+// label and usage
+func NewWorkloadRisk(counts map[string]int, runningPods int, routes int, privileged bool) *WorkloadRisk {
+	risk := &WorkloadRisk{RunningPods: runningPods, Routes: routes, Privileged: privileged, Findings: counts}
+	severity := 0
+	for label, count := range counts {
+		severity += findingWeights[label] * count
+	}
+	pods := runningPods
+	if pods > maxCountedPods {
+		pods = maxCountedPods
+	}
+	risk.Score = (1 + severity) * (1 + pods)
+	if routes > 0 {
+		risk.Score *= 3
+	}
+	if privileged {
+		risk.Score *= 2
+	}
+	return risk
+}
+
+// ParseFindingCounts reads the finding counts per summary label from the
+// annotations the annotator wrote for the last scan, whatever families the
+// mapping had then
+func ParseFindingCounts(imageAnnotations map[string]string) map[string]int {
+	counts := make(map[string]int)
+	for _, value := range imageAnnotations {
+		var annotation annotate.OpenshiftAnnotation
+		if err := json.Unmarshal([]byte(value), &annotation); err != nil || annotation.Name != "redhatinsights" {
+			continue
+		}
+		for _, summary := range annotation.Summary {
+			count, err := strconv.Atoi(summary["data"])
+			if err != nil {
+				continue
+			}
+			counts[summary["label"]] += count
+		}
+	}
+	return counts
+}
+
+// ToJSON - return json version of the risk
+func (r *WorkloadRisk) ToJSON() string {
+	str, err := json.Marshal(r)
+	if err != nil {
+		return "{}"
+	}
+	return string(str)
+}
@@ -0,0 +1,29 @@
+package annotations
+
+import "testing"
+
+func TestWorkloadRiskOrder(t *testing.T) {
+	counts := map[string]int{"critical": 1, "low": 2}
+	unused := NewWorkloadRisk(counts, 0, 0, false)
+	running := NewWorkloadRisk(counts, 3, 0, false)
+	exposed := NewWorkloadRisk(counts, 3, 1, true)
+	clean := NewWorkloadRisk(nil, 3, 1, true)
+
+	if !(unused.Score < running.Score && running.Score < exposed.Score) {
+		t.Fatalf("Expected usage to raise the score: %d, %d, %d", unused.Score, running.Score, exposed.Score)
+	}
+	if clean.Score >= exposed.Score {
+		t.Fatalf("Expected findings to raise the score: %d, %d", clean.Score, exposed.Score)
+	}
+	if NewWorkloadRisk(counts, 1000, 0, false).Score != NewWorkloadRisk(counts, maxCountedPods, 0, false).Score {
+		t.Fatal("Expected the pod count to be capped")
+	}
+}
+
+func TestParseFindingCounts(t *testing.T) {
+	values := NewAnnotator(nil, "https://insights").Annotate(testResponse, nil, "SHA123456", true)
+	counts := ParseFindingCounts(values)
+	if counts["critical"] != 1 || counts["high"] != 1 || counts["medium"] != 1 || counts["low"] != 1 {
+		t.Fatalf("Unexpected counts %v", counts)
+	}
+}
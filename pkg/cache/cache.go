@@ -0,0 +1,120 @@
+package cache
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+const DefaultCacheLocation = "/data/cache"
+
+// ResultCache stores scan reports on disk keyed by the ordered layer digests
+// of an image and the version of the scanner and rules that produced them.
+// Images with identical content therefore share a single scan.
+type ResultCache struct {
+	dir     string
+	version string
+	lock    sync.Mutex
+}
+
+// NewResultCache creates a cache rooted at dir
+func NewResultCache(dir string) *ResultCache {
+	return &ResultCache{dir: dir}
+}
+
+// NewDefaultResultCache creates a cache rooted at SCAN_CACHE_DIR, or the
+// default location when it is not set
+func NewDefaultResultCache() *ResultCache {
+	dir := os.Getenv("SCAN_CACHE_DIR")
+	if len(dir) == 0 {
+		dir = DefaultCacheLocation
+	}
+	return NewResultCache(dir)
+}
+
+// SetVersion selects the scanner version results are stored and looked up
+// for. Results produced by any other version are discarded.
+func (c *ResultCache) SetVersion(version string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.version == version {
+		return
+	}
+	c.version = version
+
+	entries, err := ioutil.ReadDir(c.dir)
+	if err != nil {
+		return
+	}
+	current := hash(version)
+	for _, entry := range entries {
+		if entry.Name() != current {
+			log.Printf("Discarding cached results from previous scanner version %s", entry.Name())
+			os.RemoveAll(filepath.Join(c.dir, entry.Name()))
+		}
+	}
+}
+
+// Get returns the cached report for an image with the given layers
+func (c *ResultCache) Get(layers []string) (string, bool) {
+	path, ok := c.path(layers)
+	if !ok {
+		return "", false
+	}
+	report, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", false
+	}
+	return string(report), true
+}
+
+// Put stores the report for an image with the given layers
+func (c *ResultCache) Put(layers []string, report string) error {
+	path, ok := c.path(layers)
+	if !ok {
+		return fmt.Errorf("Image has no layers or cache has no version")
+	}
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return err
+	}
+	// Write then rename so readers never see a partial report. Images with
+	// the same layers may be stored concurrently, each needs its own file.
+	tmp, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+	_, err = tmp.WriteString(report)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err = os.Rename(tmp.Name(), path); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return nil
+}
+
+func (c *ResultCache) path(layers []string) (string, bool) {
+	c.lock.Lock()
+	version := c.version
+	c.lock.Unlock()
+
+	if len(layers) == 0 || len(version) == 0 {
+		return "", false
+	}
+	return filepath.Join(c.dir, hash(version), hash(strings.Join(layers, "\n"))+".json"), true
+}
+
+func hash(s string) string {
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(s)))
+}
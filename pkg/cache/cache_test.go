@@ -0,0 +1,114 @@
+package cache
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func newTestCache(t *testing.T) (*ResultCache, func()) {
+	dir, err := ioutil.TempDir("", "insights-cache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewResultCache(dir), func() { os.RemoveAll(dir) }
+}
+
+func TestPutGet(t *testing.T) {
+	c, cleanup := newTestCache(t)
+	defer cleanup()
+	c.SetVersion("1.0")
+
+	layers := []string{"sha256:a", "sha256:b"}
+	if _, ok := c.Get(layers); ok {
+		t.Fatal("Expected an empty cache to miss")
+	}
+	if err := c.Put(layers, `{"reports":[]}`); err != nil {
+		t.Fatal(err)
+	}
+	report, ok := c.Get(layers)
+	if !ok || report != `{"reports":[]}` {
+		t.Fatalf("Expected the stored report, got %q %v", report, ok)
+	}
+	if _, ok := c.Get([]string{"sha256:b", "sha256:a"}); ok {
+		t.Fatal("Expected layers in another order to miss")
+	}
+}
+
+func TestPutWithoutVersionOrLayers(t *testing.T) {
+	c, cleanup := newTestCache(t)
+	defer cleanup()
+
+	if err := c.Put([]string{"sha256:a"}, "{}"); err == nil {
+		t.Fatal("Expected an error without a version")
+	}
+	c.SetVersion("1.0")
+	if err := c.Put(nil, "{}"); err == nil {
+		t.Fatal("Expected an error without layers")
+	}
+}
+
+func TestSetVersionDiscardsOtherVersions(t *testing.T) {
+	c, cleanup := newTestCache(t)
+	defer cleanup()
+
+	layers := []string{"sha256:a"}
+	c.SetVersion("1.0")
+	if err := c.Put(layers, "old"); err != nil {
+		t.Fatal(err)
+	}
+	c.SetVersion("1.0")
+	if _, ok := c.Get(layers); !ok {
+		t.Fatal("Expected the same version to keep its results")
+	}
+
+	c.SetVersion("2.0")
+	if _, ok := c.Get(layers); ok {
+		t.Fatal("Expected a new version not to see old results")
+	}
+	entries, _ := ioutil.ReadDir(c.dir)
+	if len(entries) != 0 {
+		t.Fatalf("Expected old results removed, found %d entries", len(entries))
+	}
+	c.SetVersion("1.0")
+	if _, ok := c.Get(layers); ok {
+		t.Fatal("Expected discarded results to stay gone")
+	}
+}
+
+func TestConcurrentPutSameLayers(t *testing.T) {
+	c, cleanup := newTestCache(t)
+	defer cleanup()
+	c.SetVersion("1.0")
+
+	layers := []string{"sha256:a", "sha256:b"}
+	var wait sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wait.Add(1)
+		go func(i int) {
+			defer wait.Done()
+			report := strings.Repeat(fmt.Sprintf("%d", i), 64*1024)
+			if err := c.Put(layers, report); err != nil {
+				t.Error(err)
+			}
+		}(i)
+	}
+	wait.Wait()
+
+	report, ok := c.Get(layers)
+	if !ok {
+		t.Fatal("Expected a stored report")
+	}
+	if report != strings.Repeat(report[:1], len(report)) || len(report) != 64*1024 {
+		t.Fatal("Expected one complete report, got a mixed one")
+	}
+	path, _ := c.path(layers)
+	entries, _ := ioutil.ReadDir(filepath.Dir(path))
+	if len(entries) != 1 {
+		t.Fatalf("Expected no temporary files left, found %d entries", len(entries))
+	}
+}
@@ -0,0 +1,150 @@
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+
+	kerrors "k8s.io/kubernetes/pkg/api/errors"
+
+	imageapi "github.com/openshift/origin/pkg/image/api"
+
+	"github.com/RedHatInsights/insights-ocp-controller/pkg/admission"
+	"github.com/RedHatInsights/insights-ocp-controller/pkg/policy"
+)
+
+const admissionPath = "/validate"
+
+// AdmissionOptions configures the validating admission webhook
+type AdmissionOptions struct {
+	// Address the webhook listens on
+	Address string
+	// CertFile and KeyFile hold the serving certificate
+	CertFile string
+	KeyFile  string
+	// FailClosed treats images that have not been scanned as non compliant
+	FailClosed bool
+}
+
+// NewDefaultAdmissionOptions reads the webhook options from the environment.
+// It returns nil unless ADMISSION_WEBHOOK is "true".
+func NewDefaultAdmissionOptions() *AdmissionOptions {
+	if os.Getenv("ADMISSION_WEBHOOK") != "true" {
+		return nil
+	}
+	opts := &AdmissionOptions{
+		Address:    os.Getenv("ADMISSION_LISTEN_ADDRESS"),
+		CertFile:   os.Getenv("ADMISSION_TLS_CERT"),
+		KeyFile:    os.Getenv("ADMISSION_TLS_KEY"),
+		FailClosed: os.Getenv("ADMISSION_FAILURE_POLICY") == "closed",
+	}
+	if len(opts.Address) == 0 {
+		opts.Address = ":8443"
+	}
+	if len(opts.CertFile) == 0 {
+		opts.CertFile = "/etc/insights-admission/tls.crt"
+	}
+	if len(opts.KeyFile) == 0 {
+		opts.KeyFile = "/etc/insights-admission/tls.key"
+	}
+	return opts
+}
+
+// ServeAdmission serves the validating admission webhook over HTTPS until it
+// fails
+func (c *Controller) ServeAdmission(opts *AdmissionOptions) error {
+	mux := http.NewServeMux()
+	mux.HandleFunc(admissionPath, func(w http.ResponseWriter, r *http.Request) {
+		c.handleAdmission(w, r, opts)
+	})
+	log.Printf("Serving admission webhook on %s%s", opts.Address, admissionPath)
+	return http.ListenAndServeTLS(opts.Address, opts.CertFile, opts.KeyFile, mux)
+}
+
+func (c *Controller) handleAdmission(w http.ResponseWriter, r *http.Request, opts *AdmissionOptions) {
+	var review admission.Review
+	if err := json.NewDecoder(r.Body).Decode(&review); err != nil || review.Request == nil {
+		http.Error(w, "Expected an AdmissionReview request", http.StatusBadRequest)
+		return
+	}
+	request := review.Request
+
+	// Without policies the webhook would admit everything, leave the
+	// decision to the webhook's failure policy instead
+	if !c.policiesReady() {
+		http.Error(w, "Compliance policies are not loaded yet", http.StatusServiceUnavailable)
+		return
+	}
+
+	images, err := request.ContainerImages()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	p := c.policyFor(request.Namespace)
+	results := make([]admission.ImageResult, 0, len(images))
+	for _, image := range images {
+		results = append(results, admission.ImageResult{
+			Image:   image,
+			Verdict: c.imageVerdict(request.Namespace, image, p),
+		})
+	}
+	response := admission.Decide(request.UID, results, p.EnforcementOf(), opts.FailClosed)
+	if !response.Allowed {
+		log.Printf("Denied %s of %s in %s: %s", request.Operation, request.Kind.Kind, request.Namespace, response.Result.Message)
+	}
+	for _, warning := range response.Warnings {
+		log.Printf("Warned about %s of %s in %s: %s", request.Operation, request.Kind.Kind, request.Namespace, warning)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(&admission.Review{
+		APIVersion: review.APIVersion,
+		Kind:       review.Kind,
+		Response:   response,
+	})
+}
+
+// imageVerdict judges the findings recorded on the image a container image
+// reference resolves to against the policy of the namespace it is admitted
+// to, or returns nil when the image was not scanned
+func (c *Controller) imageVerdict(namespace string, imageRef string, p *policy.Policy) *policy.Verdict {
+	image, err := c.resolveImage(namespace, imageRef)
+	if err != nil {
+		if !kerrors.IsNotFound(err) {
+			log.Printf("Error resolving image %s: %s", imageRef, err)
+		}
+		return nil
+	}
+	return admission.ImageVerdict(image.Annotations, p, c.mappingForPolicies())
+}
+
+// resolveImage finds the image a container image reference points to, by
+// digest or through the image stream tag of the same name
+func (c *Controller) resolveImage(namespace string, imageRef string) (*imageapi.Image, error) {
+	ref, err := imageapi.ParseDockerImageReference(imageRef)
+	if err != nil {
+		return nil, err
+	}
+	if len(ref.ID) > 0 {
+		return c.openshiftClient.Images().Get(ref.ID)
+	}
+	if len(ref.Namespace) > 0 {
+		namespace = ref.Namespace
+	}
+	tag := ref.Tag
+	if len(tag) == 0 {
+		tag = imageapi.DefaultImageTag
+	}
+	ist, err := c.openshiftClient.ImageStreamTags(namespace).Get(ref.Name, tag)
+	if err != nil {
+		return nil, err
+	}
+	if len(ist.Image.Name) == 0 {
+		return nil, fmt.Errorf("Image stream tag %s/%s:%s has no image", namespace, ref.Name, tag)
+	}
+	// The image embedded in the tag may omit annotations added since
+	return c.openshiftClient.Images().Get(ist.Image.Name)
+}
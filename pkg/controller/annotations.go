@@ -0,0 +1,68 @@
+package controller
+
+import (
+	"fmt"
+	"log"
+
+	osclient "github.com/openshift/origin/pkg/client"
+	imageapi "github.com/openshift/origin/pkg/image/api"
+
+	kerrors "k8s.io/kubernetes/pkg/api/errors"
+
+	"github.com/RedHatInsights/insights-ocp-controller/pkg/annotations"
+)
+
+// maxAnnotationRetries bounds the retries on resourceVersion conflicts
+const maxAnnotationRetries = 5
+
+// updateImageAnnotations sets values on the image without touching
+// annotations owned by anyone else. The update is retried on a fresh copy of
+// the image whenever it was modified concurrently.
+func (c *Controller) updateImageAnnotations(openshiftSha string, values map[string]string) error {
+	object := &imageObject{images: c.openshiftClient.Images(), name: openshiftSha}
+	return annotations.Update("image "+openshiftSha, object, values, kerrors.IsConflict, maxAnnotationRetries)
+}
+
+// imageObject reads and updates the annotations of one image
+type imageObject struct {
+	images osclient.ImageInterface
+	name   string
+	image  *imageapi.Image
+}
+
+func (o *imageObject) Get() (map[string]string, error) {
+	image, err := o.images.Get(o.name)
+	if err != nil {
+		return nil, fmt.Errorf("Error getting image %s: %v", o.name, err)
+	}
+	o.image = image
+	return image.Annotations, nil
+}
+
+func (o *imageObject) Update(values map[string]string) error {
+	o.image.Annotations = values
+	_, err := o.images.Update(o.image)
+	return err
+}
+
+// retryOnConflict runs a get and update of the named object again whenever
+// the object was modified concurrently
+func retryOnConflict(name string, update func() error) error {
+	return annotations.Retry(name, kerrors.IsConflict, maxAnnotationRetries, update)
+}
+
+// setScanStatus records the scan status of an image
+func (c *Controller) setScanStatus(openshiftSha string, status *annotations.Status) {
+	err := c.updateImageAnnotations(openshiftSha, map[string]string{annotations.StatusKey: status.ToJSON()})
+	if err != nil {
+		log.Printf("Error setting scan status of image %s to %s: %s", openshiftSha, status.Phase, err)
+	}
+}
+
+// failScanStatus records a failed scan attempt and why it failed
+func (c *Controller) failScanStatus(openshiftSha string, status *annotations.Status, reason string, message string) {
+	status.Phase = annotations.PhaseFailed
+	status.Reason = reason
+	status.Message = message
+	c.setScanStatus(openshiftSha, status)
+}
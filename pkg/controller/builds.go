@@ -0,0 +1,115 @@
+package controller
+
+import (
+	"log"
+	"time"
+
+	kapi "k8s.io/kubernetes/pkg/api"
+	"k8s.io/kubernetes/pkg/types"
+	"k8s.io/kubernetes/pkg/watch"
+
+	buildapi "github.com/openshift/origin/pkg/build/api"
+	imageapi "github.com/openshift/origin/pkg/image/api"
+
+	"github.com/RedHatInsights/insights-ocp-controller/pkg/annotations"
+)
+
+// WatchBuilds scans the output image of every build as soon as it completes,
+// ahead of the images waiting in the regular scan cycle, and annotates the
+// build with the outcome. It never returns.
+func (c *Controller) WatchBuilds() {
+	handled := make(map[types.UID]bool)
+	for {
+		// Builds that completed before the watch started are left to the
+		// regular scan cycle
+		builds, err := c.openshiftClient.Builds(kapi.NamespaceAll).List(kapi.ListOptions{})
+		if err != nil {
+			log.Printf("Error listing builds: %s", err)
+			time.Sleep(time.Minute)
+			continue
+		}
+		w, err := c.openshiftClient.Builds(kapi.NamespaceAll).Watch(kapi.ListOptions{ResourceVersion: builds.ResourceVersion})
+		if err != nil {
+			log.Printf("Error watching builds: %s", err)
+			time.Sleep(time.Minute)
+			continue
+		}
+		for event := range w.ResultChan() {
+			build, ok := event.Object.(*buildapi.Build)
+			if !ok || event.Type == watch.Deleted || build.Status.Phase != buildapi.BuildPhaseComplete || handled[build.UID] {
+				continue
+			}
+			// Only the leader schedules scans
+			if !c.Leading() {
+				continue
+			}
+			handled[build.UID] = true
+			go c.scanBuildOutput(build)
+		}
+		w.Stop()
+	}
+}
+
+// scanBuildOutput scans the image pushed by a completed build
+func (c *Controller) scanBuildOutput(build *buildapi.Build) {
+	config := c.currentConfig()
+	if config == nil {
+		log.Printf("Egg not verified yet, leaving the output of build %s/%s to the scan cycle", build.Namespace, build.Name)
+		return
+	}
+	image, err := c.buildOutputImage(build)
+	if err != nil {
+		log.Printf("Unable to resolve the output image of build %s/%s: %s", build.Namespace, build.Name, err)
+		return
+	}
+	log.Printf("Build %s/%s completed, scanning its output image %s", build.Namespace, build.Name, image.Name)
+
+	c.slots.acquire(true)
+	handled := c.processImage(config, image, false)
+	c.slots.release()
+
+	// In node mode the node that scanned the image annotates the build
+	if !handled {
+		return
+	}
+	if err := c.annotateBuild(build, image.Name); err != nil {
+		log.Printf("Error annotating build %s/%s: %s", build.Namespace, build.Name, err)
+	}
+}
+
+// buildOutputImage resolves the image a build pushed through the image
+// stream tag or image reference it pushed to
+func (c *Controller) buildOutputImage(build *buildapi.Build) (*imageapi.Image, error) {
+	to := build.Spec.Output.To
+	if to == nil {
+		return c.resolveImage(build.Namespace, build.Status.OutputDockerImageReference)
+	}
+	namespace := build.Namespace
+	if len(to.Namespace) > 0 {
+		namespace = to.Namespace
+	}
+	return c.resolveImage(namespace, to.Name)
+}
+
+// annotateBuild copies the scan status and summary of its output image to a
+// build
+func (c *Controller) annotateBuild(build *buildapi.Build, digest string) error {
+	image, err := c.openshiftClient.Images().Get(digest)
+	if err != nil {
+		return err
+	}
+	values := map[string]string{
+		annotations.StatusKey:  image.Annotations[annotations.StatusKey],
+		annotations.SummaryKey: image.Annotations[annotations.SummaryKey],
+	}
+	builds := c.openshiftClient.Builds(build.Namespace)
+	return retryOnConflict("build "+build.Namespace+"/"+build.Name, func() error {
+		current, err := builds.Get(build.Name)
+		if err != nil {
+			return err
+		}
+		current.Annotations = annotations.Merge(current.Annotations, values)
+		_, err = builds.Update(current)
+		return err
+	})
+}
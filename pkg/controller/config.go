@@ -0,0 +1,107 @@
+package controller
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	kerrors "k8s.io/kubernetes/pkg/api/errors"
+
+	"github.com/RedHatInsights/insights-ocp-controller/pkg/annotations"
+	"github.com/RedHatInsights/insights-ocp-controller/pkg/policy"
+	"github.com/RedHatInsights/insights-ocp-controller/pkg/scope"
+	"github.com/RedHatInsights/insights-ocp-controller/pkg/suppress"
+)
+
+const (
+	defaultMappingConfigMap = "insights-annotations"
+	mappingConfigMapKey     = "mapping.yaml"
+)
+
+// configMapData returns one key of the ConfigMap named by the environment
+// variable env, or defaultName, in the controller's namespace. It returns
+// false when the ConfigMap does not exist.
+func (c *Controller) configMapData(env string, defaultName string, key string) (string, bool, error) {
+	name := os.Getenv(env)
+	if len(name) == 0 {
+		name = defaultName
+	}
+	configMap, err := c.kubeClient.ConfigMaps(controllerNamespace()).Get(name)
+	if kerrors.IsNotFound(err) {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, fmt.Errorf("Error getting ConfigMap %s: %v", name, err)
+	}
+	return configMap.Data[key], true, nil
+}
+
+// loadMapping reads the annotation mapping from the ConfigMap named by
+// ANNOTATION_CONFIGMAP. Without one the default mapping is used; an invalid
+// one is reported and the previous mapping kept.
+func (c *Controller) loadMapping(previous *annotations.Mapping) *annotations.Mapping {
+	data, found, err := c.configMapData("ANNOTATION_CONFIGMAP", defaultMappingConfigMap, mappingConfigMapKey)
+	if err != nil {
+		log.Println(err)
+		return previous
+	}
+	if !found {
+		return annotations.DefaultMapping()
+	}
+	mapping, err := annotations.ParseMapping([]byte(data))
+	if err != nil {
+		log.Printf("Error in annotation mapping, keeping previous mapping: %s", err)
+		return previous
+	}
+	return mapping
+}
+
+// scanConfig is the configuration scans run with. prepare builds a new one
+// for every scan cycle and it is never modified afterwards, so the scans of
+// builds, rescan requests and scan workers can share it with the cycle.
+type scanConfig struct {
+	eggVersion   string
+	mapping      *annotations.Mapping
+	suppressions *suppress.Suppressions
+	scope        *scope.Rules
+	workloads    *workloadIndex
+	prepared     time.Time
+	// hash identifies the configuration annotations depend on when the
+	// cycle started
+	hash string
+}
+
+// configHash identifies the configuration the annotations of an image
+// depend on besides the egg: the annotation mapping, the suppressions in
+// force and the compliance policies. Images annotated with another
+// configuration are annotated again, including images whose findings were
+// suppressed by a suppression that expired since.
+func (c *Controller) configHash(config *scanConfig) string {
+	c.policyLock.RLock()
+	policies := c.policies
+	c.policyLock.RUnlock()
+
+	data, _ := json.Marshal(struct {
+		Mapping      *annotations.Mapping   `json:"mapping"`
+		Suppressions *suppress.Suppressions `json:"suppressions"`
+		Policies     *policy.Policies       `json:"policies"`
+	}{config.mapping, config.suppressions.Active(time.Now()), policies})
+	return fmt.Sprintf("%x", sha256.Sum256(data))
+}
+
+// currentConfig returns the configuration of the latest scan cycle, nil
+// until the egg was verified once
+func (c *Controller) currentConfig() *scanConfig {
+	c.configLock.RLock()
+	defer c.configLock.RUnlock()
+	return c.config
+}
+
+func (c *Controller) setConfig(config *scanConfig) {
+	c.configLock.Lock()
+	defer c.configLock.Unlock()
+	c.config = config
+}
@@ -15,6 +15,7 @@ import (
 	"github.com/fsouza/go-dockerclient"
 	osclient "github.com/openshift/origin/pkg/client"
 	"github.com/openshift/origin/pkg/cmd/util/clientcmd"
+	imageapi "github.com/openshift/origin/pkg/image/api"
 	"github.com/spf13/pflag"
 
 	kapi "k8s.io/kubernetes/pkg/api"
@@ -22,10 +23,13 @@ import (
 	kclient "k8s.io/kubernetes/pkg/client/unversioned"
 	"k8s.io/kubernetes/pkg/runtime"
 
-	iclient "github.com/RedHatInsights/insights-goapi/client"
 	"github.com/RedHatInsights/insights-goapi/common"
 	"github.com/RedHatInsights/insights-goapi/container"
-	"github.com/RedHatInsights/insights-goapi/openshift"
+
+	"github.com/RedHatInsights/insights-ocp-controller/pkg/annotations"
+	"github.com/RedHatInsights/insights-ocp-controller/pkg/cache"
+	"github.com/RedHatInsights/insights-ocp-controller/pkg/policy"
+	"github.com/RedHatInsights/insights-ocp-controller/pkg/scanner"
 )
 
 type Controller struct {
@@ -35,6 +39,24 @@ type Controller struct {
 	typer           runtime.ObjectTyper
 	f               *clientcmd.Factory
 	wait            sync.WaitGroup
+	scanner         *scanner.EggScanner
+	cache           *cache.ResultCache
+	jobs            *JobOptions
+	config          *scanConfig
+	configLock      sync.RWMutex
+	prepareLock     sync.Mutex
+	policies        *policy.Policies
+	policiesLoaded  bool
+	policyMapping   *annotations.Mapping
+	policyLock      sync.RWMutex
+	events          *eventRecorder
+	slots           *scanSlots
+	rebuilds        *RebuildOptions
+	skips           *skipCounter
+	rescans         *rescans
+	leader          *LeaderOptions
+	election        *election
+	node            *NodeOptions
 }
 
 type ScanResult struct {
@@ -47,16 +69,88 @@ func NewController(os *osclient.Client, kc *kclient.Client) *Controller {
 	f := clientcmd.New(pflag.NewFlagSet("empty", pflag.ContinueOnError))
 	mapper, typer := f.Object(false)
 
+	jobs := NewDefaultJobOptions()
+	leaderOpts := NewDefaultLeaderOptions()
+	nodeOpts := NewDefaultNodeOptions()
+	// Every node scans its own images, there is nothing to lead
+	if nodeOpts != nil && leaderOpts != nil {
+		log.Printf("Leader election is not used in node mode")
+		leaderOpts = nil
+	}
+	// Jobs scan in their own pods, so several can run at once
+	concurrency := 1
+	if jobs != nil {
+		concurrency = jobs.Concurrency
+	}
+
 	return &Controller{
 		openshiftClient: os,
 		kubeClient:      kc,
 		mapper:          mapper,
 		typer:           typer,
 		f:               f,
+		scanner:         newEggScanner(),
+		cache:           cache.NewDefaultResultCache(),
+		jobs:            jobs,
+		policyMapping:   annotations.DefaultMapping(),
+		events:          newEventRecorder(kc),
+		slots:           newScanSlots(concurrency),
+		rebuilds:        NewDefaultRebuildOptions(),
+		skips:           newSkipCounter(),
+		rescans:         newRescans(),
+		leader:          leaderOpts,
+		election:        newElection(leaderOpts),
+		node:            nodeOpts,
+	}
+}
+
+func newEggScanner() *scanner.EggScanner {
+	sandbox := scanner.NewSandbox(*scanner.NewDefaultSandboxOptions())
+	return scanner.NewEggScanner(*scanner.NewDefaultEggOptions(), sandbox)
+}
+
+// prepare verifies the egg and reloads the configuration and the workload
+// index before scanning. It returns the new configuration, or nil when
+// nothing may be scanned.
+func (c *Controller) prepare() *scanConfig {
+	// The scan cycle and the scan worker may both refresh the configuration
+	c.prepareLock.Lock()
+	defer c.prepareLock.Unlock()
+
+	// Never run an egg that does not match its signature
+	eggVersion, err := c.scanner.Verify()
+	if err != nil {
+		log.Printf("Egg verification failed, skipping scans: %s", err)
+		return nil
+	}
+	log.Printf("Verified egg version %s", eggVersion)
+
+	previous := c.currentConfig()
+	if previous == nil {
+		previous = &scanConfig{mapping: annotations.DefaultMapping()}
+	} else if previous.eggVersion != eggVersion {
+		log.Printf("Rules changed from egg version %s to %s, rescanning all images", previous.eggVersion, eggVersion)
 	}
+	c.cache.SetVersion(eggVersion)
+	config := &scanConfig{
+		eggVersion:   eggVersion,
+		mapping:      c.loadMapping(previous.mapping),
+		suppressions: c.loadSuppressions(previous.suppressions),
+		scope:        c.loadScope(previous.scope),
+		workloads:    c.buildWorkloadIndex(),
+		prepared:     time.Now(),
+	}
+	config.hash = c.configHash(config)
+	c.setConfig(config)
+	return config
 }
 
 func (c *Controller) ScanImages() {
+	config := c.prepare()
+	if config == nil {
+		return
+	}
+	eggVersion := config.eggVersion
 
 	imageList, err := c.openshiftClient.Images().List(kapi.ListOptions{})
 
@@ -70,40 +164,124 @@ func (c *Controller) ScanImages() {
 		return
 	}
 
+	imageList.Items = config.selectImages(imageList.Items)
+
+	// Images whose results are outdated go first, riskiest first
+	config.sortByScanPriority(imageList.Items)
+
+	if c.jobs != nil {
+		c.collectScanJobs()
+	}
+
+	// Scan workers on every replica take the images from here
+	if c.leader != nil && c.leader.Workers {
+		c.publishWork(config, imageList.Items)
+		return
+	}
+
 	// Get the list of images to scan
-	for _, image := range imageList.Items {
-		log.Printf("Scanning image %s %s", image.DockerImageMetadata.ID, image.DockerImageReference)
+	for i := range imageList.Items {
+		image := &imageList.Items[i]
+		if config.upToDate(image) {
+			log.Printf("Image %s already scanned with egg version %s", image.GetName(), eggVersion)
+			continue
+		}
+		if !c.Leading() {
+			log.Printf("Lost leadership, stopping the scan cycle")
+			break
+		}
+		c.slots.acquire(false)
+		c.wait.Add(1)
+		go func() {
+			defer func() {
+				c.slots.release()
+				c.wait.Done()
+			}()
+			c.processImage(config, image, false)
+		}()
+	}
+	c.wait.Wait()
+	c.skips.report()
+
+	return
+
+}
 
+// processImage schedules the scan of one image with the Chief and scans it,
+// recording each stage in the image's status annotation. Images out of the
+// scan scope, and in node mode images on other nodes or claimed by another
+// node, are skipped before the Chief is asked. A forced scan ignores earlier
+// results of the image. It returns false when the image was left to another
+// node, which then handles it and whatever waits for its scan.
+func (c *Controller) processImage(config *scanConfig, image *imageapi.Image, force bool) bool {
+	if reason := c.skipReason(config, image); len(reason) > 0 {
+		log.Printf("Skipping image %s %s: %s", image.GetName(), image.DockerImageReference, reason)
+		c.skips.add(reason)
+		return true
+	}
+
+	// In node mode only images on this node are scanned, each on one node
+	if c.node != nil {
+		if !c.imageExists(image.DockerImageMetadata.ID) {
+			return false
+		}
+		if !c.claimNodeScan(config, image.GetName(), force) {
+			return false
+		}
+		defer c.releaseNodeScan(image.GetName())
+	}
+
+	// Only the configuration changed since the last scan, the cached report
+	// is enough to annotate the image again
+	if !force && scannedVersion(image) == config.eggVersion && c.reannotate(config, image) {
+		return true
+	}
+	log.Printf("Scanning image %s %s", image.DockerImageMetadata.ID, image.DockerImageReference)
+	status := annotations.ParseStatus(image.Annotations).NextAttempt(config.eggVersion, time.Now())
+	c.setScanStatus(image.GetName(), status)
+
+	// Scan jobs pull the image on whichever node they run
+	if c.jobs == nil {
 		// Check in to schedule the scan
 		log.Printf("Checking that image exists locally first...")
-		if c.imageExists(image.DockerImageMetadata.ID) {
-			log.Printf("Image exists.")
-			log.Printf("Check in with Master Chief...")
-			if c.canScan(image.GetName()) {
-				log.Printf("Chief check-in successful.")
-				log.Printf("Beginning scan.")
-				// Scan the thing
-				err := c.scanImage(image.DockerImageMetadata.ID,
-					string(image.DockerImageReference),
-					image.DockerImageMetadata.ID,
-					image.GetName())
-				// Check back in with the Chief (Dequeue)
-				if err == nil {
-					log.Printf("Scan completed successfully")
-				} else {
-					log.Printf("Scan completed with err %s ", err)
-				}
-				log.Printf("Removing from queue...")
-				c.removeFromQueue(image.GetName())
-			}
-		} else {
+		if !c.imageExists(image.DockerImageMetadata.ID) {
 			log.Printf("Image does not exist.")
 			log.Printf("Aborting scan.")
+			c.failScanStatus(image.GetName(), status, "ImageNotPresent", "Image is not present on the controller's node")
+			return true
 		}
+		log.Printf("Image exists.")
 	}
 
-	return
+	log.Printf("Check in with Master Chief...")
+	allowed, reason := c.canScan(image.GetName(), force)
+	if !allowed {
+		c.failScanStatus(image.GetName(), status, reason, "Scan queue did not allow the scan")
+		return true
+	}
 
+	log.Printf("Chief check-in successful.")
+	log.Printf("Beginning scan.")
+	status.Phase = annotations.PhaseScanning
+	c.setScanStatus(image.GetName(), status)
+	// Scan the thing
+	c.imageEvent(config, image, kapi.EventTypeNormal, reasonScanStarted, "Insights scan started")
+	diff, err := c.scanImage(config, image, force)
+	// Check back in with the Chief (Dequeue)
+	if err == nil {
+		log.Printf("Scan completed successfully")
+		status.Phase = annotations.PhaseSucceeded
+		status.Diff = diff
+		c.setScanStatus(image.GetName(), status)
+	} else {
+		log.Printf("Scan completed with err %s (%s)", err, scanner.FailureKindOf(err))
+		c.imageEvent(config, image, kapi.EventTypeWarning, reasonScanFailed,
+			fmt.Sprintf("Insights scan failed: %s", err))
+		c.failScanStatus(image.GetName(), status, string(scanner.FailureKindOf(err)), err.Error())
+	}
+	log.Printf("Removing from queue...")
+	c.removeFromQueue(image.GetName())
+	return true
 }
 
 func (c *Controller) removeFromQueue(id string) bool {
@@ -112,7 +290,7 @@ func (c *Controller) removeFromQueue(id string) bool {
 	req, err := http.NewRequest("POST", api+"/"+id, bytes.NewBufferString("{}"))
 	if err != nil {
 		log.Printf("Error setting up new request to Master Chief:")
-		log.Fatalf(err.Error())
+		log.Fatal(err)
 	}
 	req.Header.Set("Content-Type", "application/json")
 	dequeued := false
@@ -132,7 +310,7 @@ func (c *Controller) removeFromQueue(id string) bool {
 	}
 	if maxRetriesErr != nil {
 		log.Printf("Error reading MAX_RETRIES from environment configuration:")
-		log.Printf(maxRetriesErr.Error())
+		log.Println(maxRetriesErr)
 		log.Printf("Defaulting MAX_RETRIES to 0, infinite.")
 		maxRetries = 0
 	}
@@ -148,7 +326,7 @@ func (c *Controller) removeFromQueue(id string) bool {
 	}
 	if retrySecondsErr != nil {
 		log.Printf("Error reading RETRY_SECONDS from environment configuration:")
-		log.Printf(retrySecondsErr.Error())
+		log.Println(retrySecondsErr)
 		log.Printf("Defaulting RETRY_SECONDS to 60.")
 		retrySeconds = 60
 		retrySecondsDuration = time.Duration(retrySeconds) * time.Second
@@ -170,13 +348,13 @@ func (c *Controller) removeFromQueue(id string) bool {
 		resp, err := client.Do(req)
 		if err != nil {
 			log.Printf("Dequeue Client.Do(req) Error:")
-			log.Printf(err.Error())
+			log.Println(err)
 		} else {
 			defer resp.Body.Close()
 			body, readAllErr := ioutil.ReadAll(resp.Body)
 			if readAllErr != nil {
 				log.Printf("Dequeue Client ioutil.ReadAll Error:")
-				log.Printf(readAllErr.Error())
+				log.Println(readAllErr)
 			}
 			log.Printf("Master Chief Dequeue Status: %s", resp.Status)
 			log.Printf("Master Chief Dequeue Body: %s", body)
@@ -216,16 +394,20 @@ func (c *Controller) imageExists(id string) bool {
 	return true
 }
 
-func (c *Controller) canScan(id string) bool {
+// canScan checks in with the Chief and returns whether the image may be
+// scanned now, or the reason it may not. A forced scan goes ahead even if the
+// image was scanned recently.
+func (c *Controller) canScan(id string, force bool) (bool, string) {
 	// Setup API Request
 	api := "http://" + os.Getenv("SCAN_API") + "/queue"
 	req, err := http.NewRequest("POST", api+"/"+id, bytes.NewBufferString("{}"))
 	if err != nil {
 		log.Printf("Error setting up new request to Master Chief:")
-		log.Fatalf(err.Error())
+		log.Fatal(err)
 	}
 	req.Header.Set("Content-Type", "application/json")
 	canScan := false
+	reason := ""
 
 	// Flag to stop trying to communicate with the Chief
 	// We always keep trying to check in with the Chief
@@ -242,7 +424,7 @@ func (c *Controller) canScan(id string) bool {
 	}
 	if maxRetriesErr != nil {
 		log.Printf("Error reading MAX_RETRIES from environment configuration:")
-		log.Printf(maxRetriesErr.Error())
+		log.Println(maxRetriesErr)
 		log.Printf("Defaulting MAX_RETRIES to 0, infinite.")
 		maxRetries = 0
 	}
@@ -258,7 +440,7 @@ func (c *Controller) canScan(id string) bool {
 	}
 	if retrySecondsErr != nil {
 		log.Printf("Error reading RETRY_SECONDS from environment configuration:")
-		log.Printf(retrySecondsErr.Error())
+		log.Println(retrySecondsErr)
 		log.Printf("Defaulting RETRY_SECONDS to 60.")
 		retrySeconds = 60
 		retrySecondsDuration = time.Duration(retrySeconds) * time.Second
@@ -285,13 +467,13 @@ func (c *Controller) canScan(id string) bool {
 		resp, err := client.Do(req)
 		if err != nil {
 			log.Printf("Queue Client.Do(req) Error:")
-			log.Printf(err.Error())
+			log.Println(err)
 		} else {
 			defer resp.Body.Close()
 			body, readAllErr := ioutil.ReadAll(resp.Body)
 			if readAllErr != nil {
 				log.Printf("Queue Client ioutil.ReadAll Error:")
-				log.Printf(readAllErr.Error())
+				log.Println(readAllErr)
 			}
 			log.Printf("Master Chief Queue Status: %s", resp.Status)
 			log.Printf("Master Chief Queue Body: %s", body)
@@ -305,10 +487,16 @@ func (c *Controller) canScan(id string) bool {
 			// If we get 423 then it is being scanned elsewhere
 		} else if (err == nil) && (resp.StatusCode == 423) {
 			log.Printf("Master Chief says someone else is scanning this image. Aborting.")
+			reason = "ScanInProgressElsewhere"
 			keepTrying = false
 			// If we get 412 then its been scanned in the past 24 hours
+		} else if (err == nil) && (resp.StatusCode == 412) && force {
+			log.Printf("Master Chief says this was scanned within the past 24 hours. Rescan requested, scanning anyway.")
+			canScan = true
+			keepTrying = false
 		} else if (err == nil) && (resp.StatusCode == 412) {
 			log.Printf("Master Chief says this was scanned within the past 24 hours. Aborting.")
+			reason = "ScannedRecently"
 			keepTrying = false
 			// If we get a 403 then the server has too many scan jobs going, try again after timeout
 		} else if (err == nil) && (resp.StatusCode == 403) {
@@ -322,6 +510,7 @@ func (c *Controller) canScan(id string) bool {
 			// If we have exceeded the MAX_RETRIES limit then stop
 		} else if (retryCounter >= maxRetries) && (maxRetries != 0) {
 			log.Printf("MAX_RETRIES exceeded. Stop.")
+			reason = "QueueRetriesExceeded"
 			keepTrying = false
 			// Otherwise wait, then retry
 		} else {
@@ -329,18 +518,46 @@ func (c *Controller) canScan(id string) bool {
 			time.Sleep(retrySecondsDuration)
 		}
 	}
-	return canScan
+	return canScan, reason
 }
 
-func (c *Controller) scanImage(id string, imageRef string, imageSha string, openshiftSHA string) error {
-
-	insightsReport, err := c.mountAndScan(id, imageRef, imageSha)
-	if err == nil {
+// scanImage scans the image, or reuses the report of an image with the same
+// layers unless forced, and records the results. It returns the changes since
+// the previous scan of the image.
+func (c *Controller) scanImage(config *scanConfig, image *imageapi.Image, force bool) (*annotations.Diff, error) {
+	id := image.DockerImageMetadata.ID
+	imageRef := string(image.DockerImageReference)
+	openshiftSHA := image.GetName()
+	layers := imageLayers(image)
+
+	// Images with the same layers have the same content, reuse their report
+	var insightsReport string
+	var ok bool
+	if !force {
+		insightsReport, ok = c.cache.Get(layers)
+	}
+	if ok {
+		log.Printf("Reusing cached scan results for image %s", openshiftSHA)
+	} else {
+		var err error
+		if c.jobs != nil {
+			insightsReport, err = c.scanWithJob(openshiftSHA, imageRef)
+		} else {
+			insightsReport, err = c.mountAndScan(id, imageRef, id)
+		}
+		if err != nil {
+			return nil, err
+		}
 		log.Printf("Scan successful")
-		c.postResults(insightsReport, openshiftSHA, imageRef)             //TODO handle error
-		c.annotateImage(imageSha, openshiftSHA, imageRef, insightsReport) //TODO handle error
+		if cacheErr := c.cache.Put(layers, insightsReport); cacheErr != nil {
+			log.Printf("Unable to cache scan results for image %s: %s", openshiftSHA, cacheErr)
+		}
 	}
-	return err
+
+	history, diff := scanHistory(image, insightsReport)
+	c.postResults(reportWithDiff(insightsReport, diff), openshiftSHA, imageRef) //TODO handle error
+	c.annotateImage(config, image, insightsReport, history, diff)               //TODO handle error
+	return diff, nil
 }
 
 func (c *Controller) postResults(results string, openshiftSHA string, imageRef string) {
@@ -353,58 +570,87 @@ func (c *Controller) postResults(results string, openshiftSHA string, imageRef s
 	client := &http.Client{}
 	resp, err := client.Do(req)
 	if err != nil {
-		log.Fatalf(err.Error())
+		log.Fatal(err)
 	}
 	defer resp.Body.Close()
 	log.Printf("Status: %s", resp.Status)
 }
 
-func (c *Controller) annotateImage(imageSha string, openshiftSHA string, imageRef string, annotation string) {
-	log.Printf("Annotating local docker ID %s", imageSha)
-	log.Printf("Annotating Openshift ID %s", openshiftSHA)
-	c.updateImageAnnotationInfo(openshiftSHA, annotation)
+func (c *Controller) annotateImage(config *scanConfig, image *imageapi.Image, annotation string, history annotations.History, diff *annotations.Diff) {
+	log.Printf("Annotating local docker ID %s", image.DockerImageMetadata.ID)
+	log.Printf("Annotating Openshift ID %s", image.GetName())
+	c.updateImageAnnotationInfo(config, image, annotation, history, diff, true)
 }
 
-func (c *Controller) updateImageAnnotationInfo(openshiftSha string, newInfo string) bool {
-
-	if c.openshiftClient == nil {
-		// if there's no OpenShift client, there can't be any image annotations
+// reannotate annotates an image scanned with the current egg again from its
+// cached report, after the configuration its annotations depend on changed.
+// It returns false when the report is no longer cached and the image has to
+// be scanned again.
+func (c *Controller) reannotate(config *scanConfig, image *imageapi.Image) bool {
+	report, ok := c.cache.Get(imageLayers(image))
+	history := annotations.ParseHistory(image.Annotations)
+	if !ok || len(history) == 0 {
 		return false
 	}
+	log.Printf("Configuration changed, annotating image %s again from its cached report", image.GetName())
+	return c.updateImageAnnotationInfo(config, image, report, history, nil, false)
+}
 
-	image, err := c.openshiftClient.Images().Get(openshiftSha)
-	if err != nil {
-		log.Printf("Job: Error getting image %s: %s\n", openshiftSha, err)
-		return false
-	}
+// updateImageAnnotationInfo annotates an image with the results of a scan.
+// Events and rebuilds only follow a new scan, not an image annotated again.
+func (c *Controller) updateImageAnnotationInfo(config *scanConfig, image *imageapi.Image, newInfo string, history annotations.History, diff *annotations.Diff, scanned bool) bool {
+	openshiftSha := image.GetName()
+	imageRef := string(image.DockerImageReference)
 
-	oldAnnotations := image.ObjectMeta.Annotations
-	if oldAnnotations == nil {
-		log.Printf("Image %s has no annotations - creating object.\n", openshiftSha)
-		oldAnnotations = make(map[string]string)
+	if c.openshiftClient == nil {
+		// if there's no OpenShift client, there can't be any image annotations
+		return false
 	}
 
-	annotator := annotate.NewInsightsAnnotator("0.1", c.getInsightsUILink())
 	var res common.ScanResponse
 	newInfoBytes := []byte(newInfo)
 	json.Unmarshal(newInfoBytes, &res)
-	secAnnotations := annotator.CreateSecurityAnnotation(&res, openshiftSha)
-	opsAnnotations := annotator.CreateOperationsAnnotation(&res, openshiftSha)
+	suppressed := config.suppressFindings(imageRef, &res)
 
-	annotationValues := make(map[string]string)
-	annotationValues["quality.images.openshift.io/vulnerability.redhatinsights"] = secAnnotations.ToJSON()
-	annotationValues["quality.images.openshift.io/operations.redhatinsights"] = opsAnnotations.ToJSON()
-	image.ObjectMeta.Annotations = annotationValues
+	verdict := c.evaluatePolicy(config, imageRef, &res)
+	verdictJSON, _ := json.Marshal(verdict)
+	uiLink := c.getInsightsUILink()
+	annotator := annotations.NewAnnotator(config.mapping, uiLink)
+
+	var version struct {
+		EggVersion string `json:"egg_version"`
+	}
+	json.Unmarshal(newInfoBytes, &version)
+
+	annotationValues := annotator.Annotate(&res, suppressed, openshiftSha, verdict.Compliant)
+	annotationValues[annotations.ScannerVersionKey] = version.EggVersion
+	annotationValues[annotations.ConfigHashKey] = c.configHash(config)
+	annotationValues[annotations.ComplianceKey] = string(verdictJSON)
+	annotationValues[annotations.RuleHitsKey] = annotations.RuleHitsToJSON(annotations.NewRuleHits(res.Reports))
+	annotationValues[annotations.HistoryKey] = history.ToJSON()
+	annotationValues[annotations.WorkloadRiskKey] = config.workloads.riskOf(image, config.mapping.Counts(res.Reports)).ToJSON()
+	summary := annotations.NewImageSummary(config.mapping.Counts(res.Reports),
+		config.mapping.Worst(res.Reports, annotations.SummaryWorstFindings),
+		verdict.Compliant, verdict.Policy, uiLink+"/"+openshiftSha)
+	annotationValues[annotations.SummaryKey] = annotations.MergeSummary("", nil, openshiftSha, summary)
 
 	log.Printf("Annotate with information %s", annotationValues)
 
-	image, err = c.openshiftClient.Images().Update(image)
-	if err != nil {
+	if err := c.updateImageAnnotations(openshiftSha, annotationValues); err != nil {
 		log.Printf("Error updating annotations for image: %s. %s\n", openshiftSha, err)
 		return false
 	}
 
 	log.Println("Image annotated.")
+	if scanned {
+		c.scanResultEvents(config, image, &res, verdict, diff)
+	}
+	c.propagateSummary(config, image, summary)
+	c.refreshProjectReports(config, image)
+	c.promoteImage(config, image, &res)
+	if scanned {
+		c.triggerRebuilds(config, image, history[len(history)-1].Rules)
+	}
 
 	return true
 }
@@ -420,9 +666,13 @@ func (c *Controller) mountAndScan(id string, imageRef string, imageSha string) (
 	scanOptions.DstPath = scanDirectory
 	scanOptions.Image = imageSha
 	mounter := container.NewDefaultImageMounter(*scanOptions)
-	_, image, _ := mounter.Mount()
-	scanner := iclient.NewDefaultScanner()
-	_, out, err := scanner.ScanImage(scanOptions.DstPath, image.ID)
+	_, image, err := mounter.Mount()
+	if err != nil {
+		log.Printf("ERROR: Mount failed %s", err)
+		os.RemoveAll(scanDirectory)
+		return "", err
+	}
+	_, out, err := c.scanner.ScanImage(scanOptions.DstPath, image.ID)
 	if err != nil {
 		fmt.Printf("ERROR: Scan failed %s", err)
 		os.RemoveAll(scanDirectory)
@@ -434,12 +684,21 @@ func (c *Controller) mountAndScan(id string, imageRef string, imageSha string) (
 	return report, nil
 }
 
+// imageLayers returns the ordered layer digests of an image
+func imageLayers(image *imageapi.Image) []string {
+	layers := make([]string, 0, len(image.DockerImageLayers))
+	for _, layer := range image.DockerImageLayers {
+		layers = append(layers, layer.Name)
+	}
+	return layers
+}
+
 func (c *Controller) getInsightsUILink() string {
 	routeName := os.Getenv("SCAN_UI")
 	if len(routeName) == 0 {
 		routeName = "insights-ocp-ui"
 	}
-	routeAPI := c.openshiftClient.Routes("insights-scan") //TODO read this via downward api  metadata.namespace
+	routeAPI := c.openshiftClient.Routes(controllerNamespace())
 	route, _ := routeAPI.Get(routeName)
 	log.Printf("Route HOST is %s ", route.Spec.Host)
 	return "https://" + route.Spec.Host
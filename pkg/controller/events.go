@@ -0,0 +1,116 @@
+package controller
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	kapi "k8s.io/kubernetes/pkg/api"
+	"k8s.io/kubernetes/pkg/client/record"
+	kclient "k8s.io/kubernetes/pkg/client/unversioned"
+
+	imageapi "github.com/openshift/origin/pkg/image/api"
+
+	"github.com/RedHatInsights/insights-goapi/common"
+
+	"github.com/RedHatInsights/insights-ocp-controller/pkg/annotations"
+	"github.com/RedHatInsights/insights-ocp-controller/pkg/env"
+	"github.com/RedHatInsights/insights-ocp-controller/pkg/policy"
+)
+
+const (
+	eventComponent = "insights-ocp-controller"
+
+	reasonScanStarted     = "ScanStarted"
+	reasonScanSucceeded   = "ScanSucceeded"
+	reasonScanFailed      = "ScanFailed"
+	reasonPolicyViolation = "PolicyViolation"
+	reasonNewFindings     = "NewFindings"
+)
+
+// eventRecorder emits events about an image to the image itself and to the
+// workloads that use it, dropping repeats of the same event within interval
+type eventRecorder struct {
+	recorder record.EventRecorder
+	interval time.Duration
+	sent     map[string]time.Time
+	lock     sync.Mutex
+}
+
+// newEventRecorder starts sending events through kc, from the node named by
+// NODE_NAME when set. EVENT_INTERVAL_SECONDS sets how long identical events
+// are suppressed.
+func newEventRecorder(kc *kclient.Client) *eventRecorder {
+	broadcaster := record.NewBroadcaster()
+	broadcaster.StartRecordingToSink(kc.Events(kapi.NamespaceAll))
+	return &eventRecorder{
+		recorder: broadcaster.NewRecorder(kapi.EventSource{Component: eventComponent, Host: os.Getenv("NODE_NAME")}),
+		interval: time.Duration(env.Int("EVENT_INTERVAL_SECONDS", 3600)) * time.Second,
+		sent:     make(map[string]time.Time),
+	}
+}
+
+// imageEvent records an event against the image and everything using it
+func (c *Controller) imageEvent(config *scanConfig, image *imageapi.Image, eventtype, reason, message string) {
+	if c.events == nil {
+		return
+	}
+	refs := append([]*kapi.ObjectReference{{
+		Kind:       "Image",
+		APIVersion: "v1",
+		Name:       image.Name,
+		UID:        image.UID,
+	}}, config.workloads.referencesFor(image)...)
+	for _, ref := range refs {
+		c.events.event(ref, eventtype, reason, message)
+	}
+}
+
+func (r *eventRecorder) event(ref *kapi.ObjectReference, eventtype, reason, message string) {
+	key := strings.Join([]string{ref.Kind, ref.Namespace, ref.Name, reason, message}, "/")
+	now := time.Now()
+
+	r.lock.Lock()
+	if last, ok := r.sent[key]; ok && now.Sub(last) < r.interval {
+		r.lock.Unlock()
+		return
+	}
+	r.sent[key] = now
+	// Forget events old enough to be sent again
+	for k, last := range r.sent {
+		if now.Sub(last) >= r.interval {
+			delete(r.sent, k)
+		}
+	}
+	r.lock.Unlock()
+
+	r.recorder.Event(ref, eventtype, reason, message)
+}
+
+// scanResultEvents records the outcome of a successful scan and what changed
+// since the previous one
+func (c *Controller) scanResultEvents(config *scanConfig, image *imageapi.Image, res *common.ScanResponse, verdict *policy.Verdict, diff *annotations.Diff) {
+	counts := config.mapping.Counts(res.Reports)
+	message := fmt.Sprintf("Insights scan found %d critical, %d high, %d medium and %d low findings",
+		counts["critical"], counts["high"], counts["medium"], counts["low"])
+	if diff != nil {
+		message += fmt.Sprintf(", %d new and %d resolved since the previous scan", len(diff.Added), len(diff.Resolved))
+	}
+	c.imageEvent(config, image, kapi.EventTypeNormal, reasonScanSucceeded, message)
+
+	if diff != nil && len(diff.Added) > 0 {
+		c.imageEvent(config, image, kapi.EventTypeWarning, reasonNewFindings,
+			fmt.Sprintf("Insights scan found new findings: %s", strings.Join(diff.Added, ", ")))
+	}
+
+	if !verdict.Compliant {
+		messages := make([]string, 0, len(verdict.Violations))
+		for _, violation := range verdict.Violations {
+			messages = append(messages, violation.Message)
+		}
+		c.imageEvent(config, image, kapi.EventTypeWarning, reasonPolicyViolation,
+			fmt.Sprintf("Image violates policy %s: %s", verdict.Policy, strings.Join(messages, "; ")))
+	}
+}
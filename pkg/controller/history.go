@@ -0,0 +1,49 @@
+package controller
+
+import (
+	"encoding/json"
+	"time"
+
+	imageapi "github.com/openshift/origin/pkg/image/api"
+
+	"github.com/RedHatInsights/insights-goapi/common"
+
+	"github.com/RedHatInsights/insights-ocp-controller/pkg/annotations"
+	"github.com/RedHatInsights/insights-ocp-controller/pkg/env"
+	"github.com/RedHatInsights/insights-ocp-controller/pkg/scanner"
+)
+
+// diffKey is the report field holding the changes since the previous scan
+const diffKey = "diff"
+
+// scanHistory adds the scan in report to the history recorded on the image.
+// SCAN_HISTORY_LENGTH sets how many scans are kept.
+func scanHistory(image *imageapi.Image, report string) (annotations.History, *annotations.Diff) {
+	var res common.ScanResponse
+	json.Unmarshal([]byte(report), &res)
+	var version map[string]interface{}
+	json.Unmarshal([]byte(report), &version)
+	eggVersion, _ := version[scanner.EggVersionKey].(string)
+
+	entry := annotations.NewHistoryEntry(res.Reports, eggVersion, time.Now())
+	return annotations.ParseHistory(image.Annotations).Append(entry,
+		env.Int("SCAN_HISTORY_LENGTH", annotations.DefaultHistoryLength))
+}
+
+// reportWithDiff adds the changes since the previous scan to the report
+// posted to the scan API
+func reportWithDiff(report string, diff *annotations.Diff) string {
+	if diff == nil {
+		return report
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal([]byte(report), &fields); err != nil {
+		return report
+	}
+	fields[diffKey] = diff
+	out, err := json.Marshal(fields)
+	if err != nil {
+		return report
+	}
+	return string(out)
+}
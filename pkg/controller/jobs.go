@@ -0,0 +1,356 @@
+package controller
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	kapi "k8s.io/kubernetes/pkg/api"
+	kerrors "k8s.io/kubernetes/pkg/api/errors"
+	"k8s.io/kubernetes/pkg/api/resource"
+	"k8s.io/kubernetes/pkg/apis/batch"
+	"k8s.io/kubernetes/pkg/labels"
+
+	"github.com/RedHatInsights/insights-ocp-controller/pkg/env"
+	"github.com/RedHatInsights/insights-ocp-controller/pkg/scanner"
+)
+
+const (
+	// ScanJobImageEnv is set on scan Job pods to the image they must scan
+	ScanJobImageEnv = "SCAN_JOB_IMAGE"
+
+	scanJobLabel        = "insights-scan-job"
+	scanJobOwnerLabel   = "insights-scan-job-owner"
+	scanJobPollInterval = 10 * time.Second
+	scanJobNamePrefix   = "insights-scan-"
+)
+
+// Environment passed through from the controller to its scan Jobs
+var scanJobEnv = []string{
+	"EGG", "EGG_SIGNATURE", "GPG_KEYRING",
+	"SCAN_UID", "SCAN_GID", "SCAN_CPU_SECONDS", "SCAN_MEMORY_MB", "SCAN_OPEN_FILES", "SCAN_OUTPUT_MB",
+}
+
+// JobOptions configures scanning through Kubernetes Jobs
+type JobOptions struct {
+	// Namespace the Jobs are created in
+	Namespace string
+	// Image is the controller image the Job pods run
+	Image string
+	// ServiceAccount the Job pods run as; it must allow privileged pods
+	ServiceAccount string
+	// Deadline is how long a Job may run before it is killed
+	Deadline time.Duration
+	// Concurrency is the number of Jobs that may run at once
+	Concurrency int
+	// MemoryLimit and CPULimit are the resource limits of each Job pod
+	MemoryLimit string
+	CPULimit    string
+	// Owner is the name of the controller pod, recorded on its Jobs
+	Owner string
+	// Started is when this controller started; its own Jobs created before
+	// were left behind by an earlier run
+	Started time.Time
+}
+
+// NewDefaultJobOptions reads the Job options from the environment. It
+// returns nil unless SCAN_MODE is "job" and SCAN_JOB_CONTAINER_IMAGE is set.
+func NewDefaultJobOptions() *JobOptions {
+	if os.Getenv("SCAN_MODE") != "job" {
+		return nil
+	}
+	opts := &JobOptions{
+		Namespace:      controllerNamespace(),
+		Image:          os.Getenv("SCAN_JOB_CONTAINER_IMAGE"),
+		ServiceAccount: os.Getenv("SCAN_JOB_SERVICE_ACCOUNT"),
+		Deadline:       time.Duration(env.Int("SCAN_JOB_DEADLINE_SECONDS", 1800)) * time.Second,
+		Concurrency:    env.Int("SCAN_JOB_CONCURRENCY", 4),
+		MemoryLimit:    os.Getenv("SCAN_JOB_MEMORY_LIMIT"),
+		CPULimit:       os.Getenv("SCAN_JOB_CPU_LIMIT"),
+		Owner:          os.Getenv("POD_NAME"),
+		Started:        time.Now(),
+	}
+	if len(opts.Owner) == 0 {
+		opts.Owner, _ = os.Hostname()
+	}
+	if len(opts.Image) == 0 {
+		log.Printf("SCAN_MODE is job but SCAN_JOB_CONTAINER_IMAGE is not set, scanning in the controller")
+		return nil
+	}
+	if opts.Concurrency < 1 {
+		opts.Concurrency = 1
+	}
+	return opts
+}
+
+// RunScanJob is the entry point of a scan Job pod. It scans the image named
+// by SCAN_JOB_IMAGE and writes the report to standard output, or the failure
+// kind to the termination log, and returns the process exit code.
+func RunScanJob() int {
+	imageRef := os.Getenv(ScanJobImageEnv)
+	c := &Controller{scanner: newEggScanner()}
+
+	report, err := c.mountAndScan(imageRef, imageRef, imageRef)
+	if err != nil {
+		log.Printf("Scan of %s failed: %s", imageRef, err)
+		if _, ok := err.(*scanner.ScanError); !ok {
+			err = &scanner.ScanError{Kind: scanner.FailureKindOf(err), Err: err}
+		}
+		ioutil.WriteFile(kapi.TerminationMessagePathDefault, []byte(err.Error()), 0644)
+		return 1
+	}
+	fmt.Println(report)
+	return 0
+}
+
+// scanWithJob scans an image in a Job and returns the report it produced
+func (c *Controller) scanWithJob(openshiftSHA string, imageRef string) (string, error) {
+	job, err := c.kubeClient.Batch().Jobs(c.jobs.Namespace).Create(c.newScanJob(openshiftSHA, imageRef))
+	if err != nil {
+		return "", fmt.Errorf("Unable to create scan job for %s: %v", openshiftSHA, err)
+	}
+	name := job.Name
+	log.Printf("Created scan job %s for image %s", name, openshiftSHA)
+	defer c.deleteScanJob(name)
+
+	// Give the Job's own deadline a chance to fire before giving up on it
+	timeout := time.After(c.jobs.Deadline + time.Minute)
+	for {
+		select {
+		case <-timeout:
+			return "", &scanner.ScanError{Kind: scanner.FailureResourceLimit,
+				Err: fmt.Errorf("scan job %s exceeded its %s deadline", name, c.jobs.Deadline)}
+		case <-time.After(scanJobPollInterval):
+		}
+
+		job, err = c.kubeClient.Batch().Jobs(c.jobs.Namespace).Get(name)
+		if kerrors.IsNotFound(err) {
+			return "", &scanner.ScanError{Kind: scanner.FailureScanner,
+				Err: fmt.Errorf("scan job %s was deleted before it finished", name)}
+		}
+		if err != nil {
+			log.Printf("Error getting scan job %s: %s", name, err)
+			continue
+		}
+		if job.Status.Succeeded > 0 {
+			return c.scanJobOutput(job)
+		}
+		if job.Status.Failed > 0 || jobCondition(job, batch.JobFailed) != nil {
+			return "", c.scanJobFailure(job)
+		}
+	}
+}
+
+func (c *Controller) newScanJob(openshiftSHA string, imageRef string) *batch.Job {
+	var one int32 = 1
+	deadline := int64(c.jobs.Deadline.Seconds())
+	privileged := true
+
+	env := []kapi.EnvVar{{Name: ScanJobImageEnv, Value: imageRef}}
+	for _, name := range scanJobEnv {
+		if value := os.Getenv(name); len(value) > 0 {
+			env = append(env, kapi.EnvVar{Name: name, Value: value})
+		}
+	}
+
+	limits := kapi.ResourceList{}
+	if quantity, err := resource.ParseQuantity(c.jobs.MemoryLimit); err == nil {
+		limits[kapi.ResourceMemory] = quantity
+	}
+	if quantity, err := resource.ParseQuantity(c.jobs.CPULimit); err == nil {
+		limits[kapi.ResourceCPU] = quantity
+	}
+
+	labels := map[string]string{
+		scanJobLabel:      imageLabel(openshiftSHA),
+		scanJobOwnerLabel: labelValue(c.jobs.Owner),
+	}
+	return &batch.Job{
+		ObjectMeta: kapi.ObjectMeta{
+			GenerateName: scanJobNamePrefix,
+			Labels:       labels,
+		},
+		Spec: batch.JobSpec{
+			Parallelism:           &one,
+			Completions:           &one,
+			ActiveDeadlineSeconds: &deadline,
+			Template: kapi.PodTemplateSpec{
+				ObjectMeta: kapi.ObjectMeta{Labels: labels},
+				Spec: kapi.PodSpec{
+					RestartPolicy:      kapi.RestartPolicyNever,
+					ServiceAccountName: c.jobs.ServiceAccount,
+					Containers: []kapi.Container{{
+						Name:            "scan",
+						Image:           c.jobs.Image,
+						Env:             env,
+						Resources:       kapi.ResourceRequirements{Limits: limits},
+						SecurityContext: &kapi.SecurityContext{Privileged: &privileged},
+						VolumeMounts: []kapi.VolumeMount{{
+							Name:      "docker-socket",
+							MountPath: "/var/run/docker.sock",
+						}},
+					}},
+					Volumes: []kapi.Volume{{
+						Name: "docker-socket",
+						VolumeSource: kapi.VolumeSource{
+							HostPath: &kapi.HostPathVolumeSource{Path: "/var/run/docker.sock"},
+						},
+					}},
+				},
+			},
+		},
+	}
+}
+
+// scanJobOutput reads the report from the logs of the Job's successful pod.
+// The report is the last line, after anything the pod logged.
+func (c *Controller) scanJobOutput(job *batch.Job) (string, error) {
+	pod, err := c.scanJobPod(job, kapi.PodSucceeded)
+	if err != nil {
+		return "", err
+	}
+	out, err := c.kubeClient.Pods(job.Namespace).GetLogs(pod.Name, &kapi.PodLogOptions{}).Do().Raw()
+	if err != nil {
+		return "", fmt.Errorf("Unable to read results of scan job %s: %v", job.Name, err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(out)), "\n")
+	return lines[len(lines)-1], nil
+}
+
+// scanJobFailure turns the termination message of a failed Job pod back
+// into a scan error of the same kind
+func (c *Controller) scanJobFailure(job *batch.Job) error {
+	if condition := jobCondition(job, batch.JobFailed); condition != nil && condition.Reason == "DeadlineExceeded" {
+		return &scanner.ScanError{Kind: scanner.FailureResourceLimit,
+			Err: fmt.Errorf("scan job %s: %s", job.Name, condition.Message)}
+	}
+	pod, err := c.scanJobPod(job, kapi.PodFailed)
+	if err != nil {
+		return &scanner.ScanError{Kind: scanner.FailureScanner, Err: err}
+	}
+	for _, status := range pod.Status.ContainerStatuses {
+		terminated := status.State.Terminated
+		if terminated == nil {
+			continue
+		}
+		if terminated.Reason == "OOMKilled" {
+			return &scanner.ScanError{Kind: scanner.FailureResourceLimit,
+				Err: fmt.Errorf("scan job %s ran out of memory", job.Name)}
+		}
+		kind, message := scanner.FailureScanner, terminated.Message
+		if parts := strings.SplitN(message, ": ", 2); len(parts) == 2 {
+			kind, message = scanner.FailureKind(parts[0]), parts[1]
+		}
+		return &scanner.ScanError{Kind: kind, Err: fmt.Errorf("scan job %s: %s", job.Name, message)}
+	}
+	return &scanner.ScanError{Kind: scanner.FailureScanner, Err: fmt.Errorf("scan job %s failed", job.Name)}
+}
+
+func (c *Controller) scanJobPod(job *batch.Job, phase kapi.PodPhase) (*kapi.Pod, error) {
+	selector := labels.SelectorFromSet(labels.Set{"job-name": job.Name})
+	pods, err := c.kubeClient.Pods(job.Namespace).List(kapi.ListOptions{LabelSelector: selector})
+	if err != nil {
+		return nil, fmt.Errorf("Unable to list pods of scan job %s: %v", job.Name, err)
+	}
+	for i := range pods.Items {
+		if pods.Items[i].Status.Phase == phase {
+			return &pods.Items[i], nil
+		}
+	}
+	return nil, fmt.Errorf("Scan job %s has no %s pod", job.Name, phase)
+}
+
+// deleteScanJob removes a Job together with its pods
+func (c *Controller) deleteScanJob(name string) {
+	if err := c.kubeClient.Batch().Jobs(c.jobs.Namespace).Delete(name, nil); err != nil {
+		log.Printf("Error deleting scan job %s: %s", name, err)
+	}
+	selector := labels.SelectorFromSet(labels.Set{"job-name": name})
+	pods, err := c.kubeClient.Pods(c.jobs.Namespace).List(kapi.ListOptions{LabelSelector: selector})
+	if err != nil {
+		log.Printf("Error listing pods of scan job %s: %s", name, err)
+		return
+	}
+	for _, pod := range pods.Items {
+		if err := c.kubeClient.Pods(c.jobs.Namespace).Delete(pod.Name, nil); err != nil {
+			log.Printf("Error deleting pod %s of scan job %s: %s", pod.Name, name, err)
+		}
+	}
+}
+
+// collectScanJobs deletes Jobs left behind by a previous controller run:
+// this controller's Jobs from before it started and the Jobs of controller
+// pods that no longer exist. Jobs of running scans, whether started by this
+// controller or another replica, are left alone.
+func (c *Controller) collectScanJobs() {
+	selector, err := labels.Parse(scanJobLabel)
+	if err != nil {
+		return
+	}
+	jobs, err := c.kubeClient.Batch().Jobs(c.jobs.Namespace).List(kapi.ListOptions{LabelSelector: selector})
+	if err != nil {
+		log.Printf("Error listing scan jobs: %s", err)
+		return
+	}
+	owner := labelValue(c.jobs.Owner)
+	for _, job := range jobs.Items {
+		jobOwner := job.Labels[scanJobOwnerLabel]
+		if jobOwner == owner {
+			if !job.CreationTimestamp.Time.Before(c.jobs.Started) {
+				continue
+			}
+		} else if len(jobOwner) > 0 && c.controllerPodExists(jobOwner) {
+			continue
+		}
+		log.Printf("Collecting scan job %s", job.Name)
+		c.deleteScanJob(job.Name)
+	}
+}
+
+// controllerPodExists reports whether the controller pod that created a Job
+// still exists. It errs on the side of an existing pod.
+func (c *Controller) controllerPodExists(name string) bool {
+	_, err := c.kubeClient.Pods(controllerNamespace()).Get(name)
+	if kerrors.IsNotFound(err) {
+		return false
+	}
+	if err != nil {
+		log.Printf("Error getting controller pod %s: %s", name, err)
+	}
+	return true
+}
+
+func jobCondition(job *batch.Job, conditionType batch.JobConditionType) *batch.JobCondition {
+	for i := range job.Status.Conditions {
+		condition := &job.Status.Conditions[i]
+		if condition.Type == conditionType && condition.Status == kapi.ConditionTrue {
+			return condition
+		}
+	}
+	return nil
+}
+
+// imageLabel shortens an image name to a valid label value
+func imageLabel(openshiftSHA string) string {
+	return labelValue(strings.Replace(openshiftSHA, ":", "-", -1))
+}
+
+// labelValue shortens a value to the length of a valid label value
+func labelValue(value string) string {
+	if len(value) > 63 {
+		value = value[:63]
+	}
+	return value
+}
+
+// controllerNamespace returns the namespace the controller runs in
+func controllerNamespace() string {
+	namespace := os.Getenv("POD_NAMESPACE")
+	if len(namespace) == 0 {
+		namespace = "insights-scan"
+	}
+	return namespace
+}
@@ -0,0 +1,152 @@
+package controller
+
+import (
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	kapi "k8s.io/kubernetes/pkg/api"
+	kerrors "k8s.io/kubernetes/pkg/api/errors"
+
+	"github.com/RedHatInsights/insights-ocp-controller/pkg/annotations"
+	"github.com/RedHatInsights/insights-ocp-controller/pkg/env"
+	"github.com/RedHatInsights/insights-ocp-controller/pkg/leader"
+)
+
+// LeaderOptions configures leader election between controller replicas.
+// Only the leader discovers and schedules images; followers stand by or,
+// with Workers set, scan the images the leader hands out.
+type LeaderOptions struct {
+	// Lock is the name of the ConfigMap holding the leader record
+	Lock string
+	// Identity of this replica
+	Identity string
+	// Lease is how long a leader holds the lock without renewing it
+	Lease time.Duration
+	// Workers makes every replica scan the images the leader hands out
+	Workers bool
+}
+
+// NewDefaultLeaderOptions reads the leader election options from the
+// environment. It returns nil unless LEADER_ELECTION is "true".
+func NewDefaultLeaderOptions() *LeaderOptions {
+	if os.Getenv("LEADER_ELECTION") != "true" {
+		return nil
+	}
+	opts := &LeaderOptions{
+		Lock:     os.Getenv("LEADER_ELECTION_LOCK"),
+		Identity: os.Getenv("POD_NAME"),
+		Lease:    time.Duration(env.Int("LEADER_LEASE_SECONDS", 15)) * time.Second,
+		Workers:  os.Getenv("LEADER_FOLLOWER_MODE") == "worker",
+	}
+	if len(opts.Lock) == 0 {
+		opts.Lock = "insights-ocp-controller-leader"
+	}
+	if len(opts.Identity) == 0 {
+		opts.Identity, _ = os.Hostname()
+	}
+	return opts
+}
+
+// election tracks whether this replica leads
+type election struct {
+	elector leader.Elector
+	leading bool
+	lock    sync.Mutex
+}
+
+func newElection(opts *LeaderOptions) *election {
+	if opts == nil {
+		return nil
+	}
+	return &election{elector: leader.Elector{Identity: opts.Identity, Lease: opts.Lease}}
+}
+
+// Leading reports whether this replica leads, always true without leader
+// election
+func (c *Controller) Leading() bool {
+	if c.election == nil {
+		return true
+	}
+	c.election.lock.Lock()
+	defer c.election.lock.Unlock()
+	return c.election.leading
+}
+
+// WaitForLeadership blocks until this replica leads
+func (c *Controller) WaitForLeadership() {
+	for !c.Leading() {
+		time.Sleep(time.Second)
+	}
+}
+
+// RunLeaderElection takes and renews the lock for as long as it can, and
+// tries again to take it when it cannot. It never returns without leader
+// election enabled and returns at once otherwise.
+func (c *Controller) RunLeaderElection() {
+	if c.election == nil {
+		return
+	}
+	for {
+		leading := c.tryAcquireOrRenew()
+		c.election.lock.Lock()
+		if leading != c.election.leading {
+			if leading {
+				log.Printf("%s became the leader", c.leader.Identity)
+			} else {
+				log.Printf("%s is no longer the leader", c.leader.Identity)
+			}
+		}
+		c.election.leading = leading
+		c.election.lock.Unlock()
+		time.Sleep(c.leader.Lease / 3)
+	}
+}
+
+// tryAcquireOrRenew writes this replica's record to the lock unless another
+// replica holds a valid lease. Concurrent writers are told apart by the
+// ConfigMap's resourceVersion.
+func (c *Controller) tryAcquireOrRenew() bool {
+	configMaps := c.kubeClient.ConfigMaps(controllerNamespace())
+	elector := &c.election.elector
+	now := time.Now()
+
+	configMap, err := configMaps.Get(c.leader.Lock)
+	if kerrors.IsNotFound(err) {
+		record := elector.Next(nil, now)
+		_, err = configMaps.Create(&kapi.ConfigMap{
+			ObjectMeta: kapi.ObjectMeta{
+				Name:        c.leader.Lock,
+				Namespace:   controllerNamespace(),
+				Annotations: map[string]string{leader.RecordKey: record.ToJSON()},
+			},
+		})
+		if err != nil {
+			if !kerrors.IsAlreadyExists(err) {
+				log.Printf("Error creating leader lock %s: %s", c.leader.Lock, err)
+			}
+			return false
+		}
+		elector.Observe(record, now)
+		return true
+	}
+	if err != nil {
+		log.Printf("Error getting leader lock %s: %s", c.leader.Lock, err)
+		return false
+	}
+
+	record := elector.Next(leader.Parse(configMap.Annotations[leader.RecordKey]), now)
+	if record == nil {
+		return false
+	}
+	configMap.Annotations = annotations.Merge(configMap.Annotations, map[string]string{leader.RecordKey: record.ToJSON()})
+	if _, err = configMaps.Update(configMap); err != nil {
+		if !kerrors.IsConflict(err) {
+			log.Printf("Error updating leader lock %s: %s", c.leader.Lock, err)
+		}
+		return false
+	}
+	elector.Observe(record, now)
+	return true
+}
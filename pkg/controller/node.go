@@ -0,0 +1,77 @@
+package controller
+
+import (
+	"log"
+	"os"
+	"time"
+
+	"github.com/RedHatInsights/insights-ocp-controller/pkg/annotations"
+	"github.com/RedHatInsights/insights-ocp-controller/pkg/env"
+)
+
+// NodeOptions configures the node local mode, in which a controller runs on
+// every node from a DaemonSet and scans the images present on its node
+type NodeOptions struct {
+	// NodeName is the node this instance runs on, from the downward API
+	NodeName string
+	// ClaimTimeout is how long a node's claim on an image lasts before
+	// another node may scan it
+	ClaimTimeout time.Duration
+}
+
+// NewDefaultNodeOptions reads the node local options from the environment.
+// It returns nil unless SCAN_MODE is "node" and NODE_NAME is set.
+func NewDefaultNodeOptions() *NodeOptions {
+	if os.Getenv("SCAN_MODE") != "node" {
+		return nil
+	}
+	opts := &NodeOptions{
+		NodeName:     os.Getenv("NODE_NAME"),
+		ClaimTimeout: time.Duration(env.Int("SCAN_NODE_CLAIM_SECONDS", 3600)) * time.Second,
+	}
+	if len(opts.NodeName) == 0 {
+		log.Printf("SCAN_MODE is node but NODE_NAME is not set, scanning as a single controller")
+		return nil
+	}
+	return opts
+}
+
+// claimNodeScan makes this node the only one to scan an image until the scan
+// finishes or the claim times out. The image's resourceVersion makes sure
+// only one of several nodes claiming at once succeeds. Unless forced, images
+// another node already annotated with the current egg and configuration are
+// not claimed.
+func (c *Controller) claimNodeScan(config *scanConfig, digest string, force bool) bool {
+	image, err := c.openshiftClient.Images().Get(digest)
+	if err != nil {
+		log.Printf("Error getting image %s: %s", digest, err)
+		return false
+	}
+	if !force && config.upToDate(image) {
+		return false
+	}
+	now := time.Now()
+	work := annotations.ParseWork(image.Annotations)
+	if work != nil && !work.Claimable(now, c.node.ClaimTimeout) && work.ClaimedBy != c.node.NodeName {
+		log.Printf("Image %s is being scanned on node %s", digest, work.ClaimedBy)
+		return false
+	}
+	if work == nil {
+		work = &annotations.Work{Requested: now}
+	}
+	work.ClaimedBy = c.node.NodeName
+	work.Claimed = now
+	image.Annotations = annotations.Merge(image.Annotations, map[string]string{annotations.WorkKey: work.ToJSON()})
+	if _, err = c.openshiftClient.Images().Update(image); err != nil {
+		log.Printf("Image %s was claimed by another node", digest)
+		return false
+	}
+	return true
+}
+
+// releaseNodeScan removes this node's claim on an image
+func (c *Controller) releaseNodeScan(digest string) {
+	if err := c.updateImageAnnotations(digest, map[string]string{annotations.WorkKey: ""}); err != nil {
+		log.Printf("Error releasing the claim on image %s: %s", digest, err)
+	}
+}
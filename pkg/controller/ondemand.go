@@ -0,0 +1,210 @@
+package controller
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	kapi "k8s.io/kubernetes/pkg/api"
+
+	imageapi "github.com/openshift/origin/pkg/image/api"
+
+	"github.com/RedHatInsights/insights-ocp-controller/pkg/annotations"
+	"github.com/RedHatInsights/insights-ocp-controller/pkg/env"
+)
+
+const rescanPath = "/rescan"
+
+// rescans tracks the images with a requested rescan in progress
+type rescans struct {
+	active map[string]bool
+	lock   sync.Mutex
+}
+
+func newRescans() *rescans {
+	return &rescans{active: make(map[string]bool)}
+}
+
+// start returns false if a rescan of the image is already in progress
+func (r *rescans) start(digest string) bool {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if r.active[digest] {
+		return false
+	}
+	r.active[digest] = true
+	return true
+}
+
+func (r *rescans) finish(digest string) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	delete(r.active, digest)
+}
+
+// rescan scans an image ahead of every regular scan, ignoring earlier
+// results, then runs clear to remove the request. In node mode only the node
+// that scanned the image removes the request.
+func (c *Controller) rescan(image *imageapi.Image, clear func() error) error {
+	config := c.currentConfig()
+	if config == nil {
+		return fmt.Errorf("Egg not verified yet")
+	}
+	if !c.rescans.start(image.Name) {
+		return nil
+	}
+	log.Printf("Rescan of image %s requested", image.Name)
+	go func() {
+		defer c.rescans.finish(image.Name)
+		c.slots.acquire(true)
+		handled := c.processImage(config, image, true)
+		c.slots.release()
+		if handled && clear != nil {
+			if err := clear(); err != nil {
+				log.Printf("Error clearing the rescan request of image %s: %s", image.Name, err)
+			}
+		}
+	}()
+	return nil
+}
+
+// WatchRescans polls every RESCAN_POLL_SECONDS for images and image stream
+// tags annotated with a rescan request. It never returns.
+func (c *Controller) WatchRescans() {
+	interval := time.Duration(env.Int("RESCAN_POLL_SECONDS", 30)) * time.Second
+	for {
+		// Only the leader schedules scans
+		if c.Leading() {
+			c.requestedImageRescans()
+			c.requestedTagRescans()
+		}
+		time.Sleep(interval)
+	}
+}
+
+func (c *Controller) requestedImageRescans() {
+	images, err := c.openshiftClient.Images().List(kapi.ListOptions{})
+	if err != nil {
+		log.Printf("Error listing images for rescan requests: %s", err)
+		return
+	}
+	for i := range images.Items {
+		image := &images.Items[i]
+		if _, ok := image.Annotations[annotations.RescanKey]; !ok {
+			continue
+		}
+		digest := image.Name
+		err := c.rescan(image, func() error {
+			return c.updateImageAnnotations(digest, map[string]string{annotations.RescanKey: ""})
+		})
+		if err != nil {
+			log.Printf("Unable to rescan image %s: %s", digest, err)
+		}
+	}
+}
+
+func (c *Controller) requestedTagRescans() {
+	streams, err := c.openshiftClient.ImageStreams(kapi.NamespaceAll).List(kapi.ListOptions{})
+	if err != nil {
+		log.Printf("Error listing image streams for rescan requests: %s", err)
+		return
+	}
+	for i := range streams.Items {
+		stream := &streams.Items[i]
+		for tag, ref := range stream.Spec.Tags {
+			if _, ok := ref.Annotations[annotations.RescanKey]; !ok {
+				continue
+			}
+			if err := c.rescanTag(stream.Namespace, stream.Name, tag, true); err != nil {
+				log.Printf("Unable to rescan image stream tag %s/%s:%s: %s", stream.Namespace, stream.Name, tag, err)
+			}
+		}
+	}
+}
+
+// rescanTag rescans the image an image stream tag points at, removing the
+// rescan annotation from the tag afterwards if clear is set
+func (c *Controller) rescanTag(namespace string, stream string, tag string, clear bool) error {
+	tags := c.openshiftClient.ImageStreamTags(namespace)
+	ist, err := tags.Get(stream, tag)
+	if err != nil {
+		return err
+	}
+	image, err := c.openshiftClient.Images().Get(ist.Image.Name)
+	if err != nil {
+		return err
+	}
+	if !clear {
+		return c.rescan(image, nil)
+	}
+	return c.rescan(image, func() error {
+		return retryOnConflict(fmt.Sprintf("image stream tag %s/%s:%s", namespace, stream, tag), func() error {
+			ist, err := tags.Get(stream, tag)
+			if err != nil {
+				return err
+			}
+			ist.Annotations = annotations.Merge(ist.Annotations, map[string]string{annotations.RescanKey: ""})
+			if ist.Tag != nil {
+				ist.Tag.Annotations = annotations.Merge(ist.Tag.Annotations, map[string]string{annotations.RescanKey: ""})
+			}
+			_, err = tags.Update(ist)
+			return err
+		})
+	})
+}
+
+// ServeRescans serves the local rescan endpoint on RESCAN_LISTEN_ADDRESS,
+// 127.0.0.1:8081 by default, until it fails. POST /rescan?image=<digest> or
+// /rescan?tag=<namespace>/<stream>:<tag> requests a rescan.
+func (c *Controller) ServeRescans() error {
+	address := os.Getenv("RESCAN_LISTEN_ADDRESS")
+	if len(address) == 0 {
+		address = "127.0.0.1:8081"
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc(rescanPath, c.handleRescan)
+	log.Printf("Serving rescan requests on %s%s", address, rescanPath)
+	return http.ListenAndServe(address, mux)
+}
+
+func (c *Controller) handleRescan(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "Use POST", http.StatusMethodNotAllowed)
+		return
+	}
+	if !c.Leading() {
+		http.Error(w, "Not the leader, send the request to the leading replica", http.StatusServiceUnavailable)
+		return
+	}
+	var err error
+	if digest := r.URL.Query().Get("image"); len(digest) > 0 {
+		var image *imageapi.Image
+		image, err = c.openshiftClient.Images().Get(digest)
+		if err == nil {
+			err = c.rescan(image, nil)
+		}
+	} else if tag := r.URL.Query().Get("tag"); len(tag) > 0 {
+		parts := strings.SplitN(tag, "/", 2)
+		streamTag := []string{}
+		if len(parts) == 2 {
+			streamTag = strings.SplitN(parts[1], ":", 2)
+		}
+		if len(streamTag) != 2 {
+			http.Error(w, "Expected tag=<namespace>/<stream>:<tag>", http.StatusBadRequest)
+			return
+		}
+		err = c.rescanTag(parts[0], streamTag[0], streamTag[1], false)
+	} else {
+		http.Error(w, "Expected an image or tag parameter", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
@@ -0,0 +1,123 @@
+package controller
+
+import (
+	"log"
+	"time"
+
+	imageapi "github.com/openshift/origin/pkg/image/api"
+
+	"github.com/RedHatInsights/insights-goapi/common"
+
+	"github.com/RedHatInsights/insights-ocp-controller/pkg/annotations"
+	"github.com/RedHatInsights/insights-ocp-controller/pkg/env"
+	"github.com/RedHatInsights/insights-ocp-controller/pkg/policy"
+)
+
+const (
+	defaultPolicyConfigMap = "insights-policy"
+	policyConfigMapKey     = "policy.yaml"
+)
+
+// WatchPolicies loads the compliance policies and the annotation mapping
+// their severities refer to at once and reloads them every
+// POLICY_RELOAD_SECONDS. It runs on every replica, leading or not, and
+// whether or not the egg could be verified, so that the admission webhook
+// always enforces the current policies wherever it is served. It never
+// returns.
+func (c *Controller) WatchPolicies() {
+	interval := time.Duration(env.Int("POLICY_RELOAD_SECONDS", 60)) * time.Second
+	for {
+		c.loadPolicies()
+		c.setPolicyMapping(c.loadMapping(c.mappingForPolicies()))
+		time.Sleep(interval)
+	}
+}
+
+// loadPolicies reads the compliance policies from the ConfigMap named by
+// POLICY_CONFIGMAP in the controller's namespace. Invalid policies are
+// reported and the previously loaded ones kept.
+func (c *Controller) loadPolicies() {
+	data, found, err := c.configMapData("POLICY_CONFIGMAP", defaultPolicyConfigMap, policyConfigMapKey)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	if !found {
+		log.Printf("No policy ConfigMap, every image is compliant")
+		c.setPolicies(nil)
+		return
+	}
+
+	policies, err := policy.Parse([]byte(data))
+	if err != nil {
+		log.Printf("Error in policy ConfigMap, keeping previous policies: %s", err)
+		return
+	}
+	c.setPolicies(policies)
+}
+
+// setPolicies replaces the policies, which the admission webhook reads
+// concurrently
+func (c *Controller) setPolicies(policies *policy.Policies) {
+	c.policyLock.Lock()
+	defer c.policyLock.Unlock()
+	c.policies = policies
+	c.policiesLoaded = true
+}
+
+func (c *Controller) setPolicyMapping(mapping *annotations.Mapping) {
+	c.policyLock.Lock()
+	defer c.policyLock.Unlock()
+	c.policyMapping = mapping
+}
+
+// mappingForPolicies returns the annotation mapping policies are judged with
+// outside of scans
+func (c *Controller) mappingForPolicies() *annotations.Mapping {
+	c.policyLock.RLock()
+	defer c.policyLock.RUnlock()
+	return c.policyMapping
+}
+
+// policiesReady reports whether the policies were loaded at least once.
+// Before that no policy can be told apart from policies not known yet.
+func (c *Controller) policiesReady() bool {
+	c.policyLock.RLock()
+	defer c.policyLock.RUnlock()
+	return c.policiesLoaded
+}
+
+// policyFor returns the policy of a namespace
+func (c *Controller) policyFor(namespace string) *policy.Policy {
+	c.policyLock.RLock()
+	policies := c.policies
+	c.policyLock.RUnlock()
+	return policies.For(c.namespaceLabels(namespace))
+}
+
+// evaluatePolicy judges the scan results against the policy selected by the
+// labels of the namespace the image was pushed to
+func (c *Controller) evaluatePolicy(config *scanConfig, imageRef string, res *common.ScanResponse) *policy.Verdict {
+	return c.policyFor(imageNamespace(imageRef)).Evaluate(res.Reports, config.mapping)
+}
+
+func (c *Controller) namespaceLabels(namespace string) map[string]string {
+	if len(namespace) == 0 {
+		return nil
+	}
+	ns, err := c.kubeClient.Namespaces().Get(namespace)
+	if err != nil {
+		log.Printf("Error getting namespace %s: %s", namespace, err)
+		return nil
+	}
+	return ns.Labels
+}
+
+// imageNamespace returns the namespace part of an image reference
+func imageNamespace(imageRef string) string {
+	ref, err := imageapi.ParseDockerImageReference(imageRef)
+	if err != nil {
+		return ""
+	}
+	return ref.Namespace
+}
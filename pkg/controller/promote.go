@@ -0,0 +1,130 @@
+package controller
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	kapi "k8s.io/kubernetes/pkg/api"
+	kerrors "k8s.io/kubernetes/pkg/api/errors"
+
+	imageapi "github.com/openshift/origin/pkg/image/api"
+
+	"github.com/RedHatInsights/insights-goapi/common"
+
+	"github.com/RedHatInsights/insights-ocp-controller/pkg/annotations"
+	"github.com/RedHatInsights/insights-ocp-controller/pkg/policy"
+)
+
+const reasonPromoted = "Promoted"
+
+// promoteImage moves the target tags of the image streams that opted in to
+// promotion to the image, when the image is the current image of the source
+// tag and passes the policy of the stream's namespace
+func (c *Controller) promoteImage(config *scanConfig, image *imageapi.Image, res *common.ScanResponse) {
+	streams := make(map[string]imageStreamTag)
+	// The stream the image was pushed to, which the workload index may
+	// not know yet for a fresh build
+	if ref, err := imageapi.ParseDockerImageReference(string(image.DockerImageReference)); err == nil && len(ref.Namespace) > 0 {
+		streams[ref.Namespace+"/"+ref.Name] = imageStreamTag{namespace: ref.Namespace, stream: ref.Name}
+	}
+	for _, tag := range config.workloads.tagsFor(image) {
+		streams[tag.namespace+"/"+tag.stream] = tag
+	}
+
+	for _, tag := range streams {
+		stream, err := c.openshiftClient.ImageStreams(tag.namespace).Get(tag.stream)
+		if err != nil {
+			if !kerrors.IsNotFound(err) {
+				log.Printf("Error getting image stream %s/%s: %s", tag.namespace, tag.stream, err)
+			}
+			continue
+		}
+		for source, target := range annotations.ParsePromotions(stream.Annotations[annotations.PromoteKey]) {
+			if current := imageapi.LatestTaggedImage(stream, source); current == nil || current.Image != image.Name {
+				continue
+			}
+			verdict := c.policyFor(tag.namespace).Evaluate(res.Reports, config.mapping)
+			if !verdict.Compliant {
+				log.Printf("Not promoting %s/%s:%s to %s, image %s violates policy %s",
+					tag.namespace, tag.stream, source, target, image.Name, verdict.Policy)
+				continue
+			}
+			if err := c.promote(config, image, tag.namespace, tag.stream, source, target, verdict); err != nil {
+				log.Printf("Error promoting %s/%s:%s to %s: %s", tag.namespace, tag.stream, source, target, err)
+			}
+		}
+	}
+}
+
+// promote points the target tag at the image unless it already points at a
+// newer one
+func (c *Controller) promote(config *scanConfig, image *imageapi.Image, namespace, stream, source, target string, verdict *policy.Verdict) error {
+	reason := "No policy applies to the namespace"
+	if len(verdict.Policy) > 0 {
+		reason = fmt.Sprintf("Image passed policy %s", verdict.Policy)
+	}
+	promotion := &annotations.Promotion{
+		Image:      image.Name,
+		From:       fmt.Sprintf("%s:%s", stream, source),
+		PromotedBy: eventComponent,
+		Policy:     verdict.Policy,
+		Reason:     reason,
+		Promoted:   time.Now(),
+	}
+	from := &kapi.ObjectReference{Kind: "ImageStreamImage", Namespace: namespace, Name: stream + "@" + image.Name}
+	tags := c.openshiftClient.ImageStreamTags(namespace)
+	name := fmt.Sprintf("%s/%s:%s", namespace, stream, target)
+
+	promoted := false
+	err := retryOnConflict("image stream tag "+name, func() error {
+		ist, err := tags.Get(stream, target)
+		if kerrors.IsNotFound(err) {
+			_, err = tags.Create(&imageapi.ImageStreamTag{
+				ObjectMeta: kapi.ObjectMeta{
+					Name:        stream + ":" + target,
+					Namespace:   namespace,
+					Annotations: map[string]string{annotations.PromotionKey: promotion.ToJSON()},
+				},
+				Tag: &imageapi.TagReference{Name: target, From: from},
+			})
+			promoted = err == nil
+			return err
+		}
+		if err != nil {
+			return err
+		}
+		if ist.Image.Name == image.Name {
+			return nil
+		}
+		if newer, err := c.newerImage(ist.Image.Name, image); err != nil || newer {
+			if err == nil {
+				log.Printf("Not promoting %s to %s, it points at a newer image %s", image.Name, name, ist.Image.Name)
+			}
+			return err
+		}
+
+		ist.Annotations = annotations.Merge(ist.Annotations, map[string]string{annotations.PromotionKey: promotion.ToJSON()})
+		ist.Tag = &imageapi.TagReference{Name: target, Annotations: ist.Annotations, From: from}
+		_, err = tags.Update(ist)
+		promoted = err == nil
+		return err
+	})
+	if promoted {
+		log.Printf("Promoted image %s to %s", image.Name, name)
+		c.imageEvent(config, image, kapi.EventTypeNormal, reasonPromoted, fmt.Sprintf("Promoted to %s: %s", name, reason))
+	}
+	return err
+}
+
+// newerImage reports whether the image named digest was created after image
+func (c *Controller) newerImage(digest string, image *imageapi.Image) (bool, error) {
+	current, err := c.openshiftClient.Images().Get(digest)
+	if kerrors.IsNotFound(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return current.DockerImageMetadata.Created.After(image.DockerImageMetadata.Created.Time), nil
+}
@@ -0,0 +1,81 @@
+package controller
+
+import (
+	"fmt"
+	"log"
+
+	kapi "k8s.io/kubernetes/pkg/api"
+
+	imageapi "github.com/openshift/origin/pkg/image/api"
+
+	"github.com/RedHatInsights/insights-ocp-controller/pkg/annotations"
+)
+
+// propagateSummary puts the compact scan summary of an image on the image
+// stream tags pointing at it and on the pods and deployment configs running
+// it, where project users can read it
+func (c *Controller) propagateSummary(config *scanConfig, image *imageapi.Image, summary *annotations.ImageSummary) {
+	for _, tag := range config.workloads.tagsFor(image) {
+		if err := c.annotateImageStreamTag(tag, image.Name, summary); err != nil {
+			log.Printf("Error annotating image stream tag %s/%s:%s: %s", tag.namespace, tag.stream, tag.tag, err)
+		}
+	}
+	for _, ref := range config.workloads.referencesFor(image) {
+		var err error
+		switch ref.Kind {
+		case "Pod":
+			err = c.annotatePod(ref, image.Name, summary)
+		case "DeploymentConfig":
+			err = c.annotateDeploymentConfig(ref, image.Name, summary)
+		}
+		if err != nil {
+			log.Printf("Error annotating %s %s/%s: %s", ref.Kind, ref.Namespace, ref.Name, err)
+		}
+	}
+}
+
+func (c *Controller) annotateImageStreamTag(tag imageStreamTag, digest string, summary *annotations.ImageSummary) error {
+	name := fmt.Sprintf("image stream tag %s/%s:%s", tag.namespace, tag.stream, tag.tag)
+	return retryOnConflict(name, func() error {
+		ist, err := c.openshiftClient.ImageStreamTags(tag.namespace).Get(tag.stream, tag.tag)
+		if err != nil {
+			return err
+		}
+		// A tag points at a single image, so only its summary is kept
+		value := annotations.MergeSummary(ist.Annotations[annotations.SummaryKey], nil, digest, summary)
+		ist.Annotations = annotations.Merge(ist.Annotations, map[string]string{annotations.SummaryKey: value})
+		_, err = c.openshiftClient.ImageStreamTags(tag.namespace).Update(ist)
+		return err
+	})
+}
+
+func (c *Controller) annotatePod(ref *kapi.ObjectReference, digest string, summary *annotations.ImageSummary) error {
+	return retryOnConflict("pod "+ref.Namespace+"/"+ref.Name, func() error {
+		pod, err := c.kubeClient.Pods(ref.Namespace).Get(ref.Name)
+		if err != nil {
+			return err
+		}
+		value := annotations.MergeSummary(pod.Annotations[annotations.SummaryKey],
+			digests(podImageRefs(pod)), digest, summary)
+		pod.Annotations = annotations.Merge(pod.Annotations, map[string]string{annotations.SummaryKey: value})
+		_, err = c.kubeClient.Pods(ref.Namespace).Update(pod)
+		return err
+	})
+}
+
+func (c *Controller) annotateDeploymentConfig(ref *kapi.ObjectReference, digest string, summary *annotations.ImageSummary) error {
+	return retryOnConflict("deployment config "+ref.Namespace+"/"+ref.Name, func() error {
+		dc, err := c.openshiftClient.DeploymentConfigs(ref.Namespace).Get(ref.Name)
+		if err != nil {
+			return err
+		}
+		refs := []string{}
+		if dc.Spec.Template != nil {
+			refs = podSpecImageRefs(&dc.Spec.Template.Spec)
+		}
+		value := annotations.MergeSummary(dc.Annotations[annotations.SummaryKey], digests(refs), digest, summary)
+		dc.Annotations = annotations.Merge(dc.Annotations, map[string]string{annotations.SummaryKey: value})
+		_, err = c.openshiftClient.DeploymentConfigs(ref.Namespace).Update(dc)
+		return err
+	})
+}
@@ -0,0 +1,168 @@
+package controller
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	kapi "k8s.io/kubernetes/pkg/api"
+
+	buildapi "github.com/openshift/origin/pkg/build/api"
+	imageapi "github.com/openshift/origin/pkg/image/api"
+
+	"github.com/RedHatInsights/insights-ocp-controller/pkg/annotations"
+	"github.com/RedHatInsights/insights-ocp-controller/pkg/env"
+	"github.com/RedHatInsights/insights-ocp-controller/pkg/rebuild"
+)
+
+const reasonRebuildTriggered = "RebuildTriggered"
+
+// RebuildOptions configures rebuilds of images whose base image was fixed
+type RebuildOptions struct {
+	// Interval is the least time between two rebuilds of a build config
+	Interval time.Duration
+	// limiter caps the rebuilds triggered across the cluster
+	limiter *rebuild.Limiter
+}
+
+// NewDefaultRebuildOptions reads the rebuild options from the environment.
+// It returns nil unless REBUILD_TRIGGERS is "true".
+func NewDefaultRebuildOptions() *RebuildOptions {
+	if os.Getenv("REBUILD_TRIGGERS") != "true" {
+		return nil
+	}
+	return &RebuildOptions{
+		Interval: time.Duration(env.Int("REBUILD_INTERVAL_SECONDS", 86400)) * time.Second,
+		limiter:  rebuild.NewLimiter(env.Int("REBUILD_MAX_PER_HOUR", 5), time.Hour),
+	}
+}
+
+// triggerRebuilds rebuilds the images built on an older image of the same
+// repository as base when base, whose scan hit baseRules, no longer hits rules
+// they inherited from it
+func (c *Controller) triggerRebuilds(config *scanConfig, base *imageapi.Image, baseRules []string) {
+	if c.rebuilds == nil {
+		return
+	}
+	repositories := imageRepositories(string(base.DockerImageReference))
+	if len(repositories) == 0 {
+		return
+	}
+	images, err := c.openshiftClient.Images().List(kapi.ListOptions{})
+	if err != nil {
+		log.Printf("Error listing images for rebuilds: %s", err)
+		return
+	}
+
+	baseLayers := imageLayers(base)
+	rebuilt := make(map[string]bool)
+	for i := range images.Items {
+		old := &images.Items[i]
+		oldRepositories := imageRepositories(string(old.DockerImageReference))
+		if old.Name == base.Name || len(oldRepositories) == 0 || oldRepositories[0] != repositories[0] ||
+			!old.DockerImageMetadata.Created.Before(base.DockerImageMetadata.Created) {
+			continue
+		}
+		oldRules := lastScanRules(old)
+		oldLayers := imageLayers(old)
+		for j := range images.Items {
+			dependent := &images.Items[j]
+			layers := imageLayers(dependent)
+			if rebuilt[dependent.Name] || !rebuild.IsBase(oldLayers, layers) || rebuild.IsBase(baseLayers, layers) {
+				continue
+			}
+			fixed := rebuild.Fixed(oldRules, baseRules, lastScanRules(dependent))
+			if len(fixed) == 0 {
+				continue
+			}
+			rebuilt[dependent.Name] = true
+			c.rebuildImage(config, dependent, base, fixed)
+		}
+	}
+}
+
+// lastScanRules returns the rules hit by the last scan of an image
+func lastScanRules(image *imageapi.Image) []string {
+	history := annotations.ParseHistory(image.Annotations)
+	if len(history) == 0 {
+		return nil
+	}
+	return history[len(history)-1].Rules
+}
+
+// rebuildImage instantiates the build configs that push to the image stream
+// tags of an image
+func (c *Controller) rebuildImage(config *scanConfig, image *imageapi.Image, base *imageapi.Image, fixed []string) {
+	for _, tag := range config.workloads.tagsFor(image) {
+		buildConfigs, err := c.openshiftClient.BuildConfigs(tag.namespace).List(kapi.ListOptions{})
+		if err != nil {
+			log.Printf("Error listing build configs in %s: %s", tag.namespace, err)
+			continue
+		}
+		for i := range buildConfigs.Items {
+			bc := &buildConfigs.Items[i]
+			if buildsTag(bc, tag) {
+				c.instantiateRebuild(config, bc, image, base, fixed)
+			}
+		}
+	}
+}
+
+// buildsTag reports whether a build config pushes to an image stream tag
+func buildsTag(bc *buildapi.BuildConfig, tag imageStreamTag) bool {
+	to := bc.Spec.Output.To
+	if to == nil || to.Kind != "ImageStreamTag" {
+		return false
+	}
+	namespace := to.Namespace
+	if len(namespace) == 0 {
+		namespace = bc.Namespace
+	}
+	return namespace == tag.namespace && to.Name == tag.stream+":"+tag.tag
+}
+
+func (c *Controller) instantiateRebuild(config *scanConfig, bc *buildapi.BuildConfig, image *imageapi.Image, base *imageapi.Image, fixed []string) {
+	name := bc.Namespace + "/" + bc.Name
+	now := time.Now()
+	if last := annotations.ParseRebuild(bc.Annotations); last != nil && now.Sub(last.Triggered) < c.rebuilds.Interval {
+		log.Printf("Not rebuilding %s, it was rebuilt at %s", name, last.Triggered)
+		return
+	}
+	if !c.rebuilds.limiter.Allow(now) {
+		log.Printf("Not rebuilding %s, too many rebuilds in the last hour", name)
+		return
+	}
+
+	message := fmt.Sprintf("Base image %s fixed %s", base.Name, strings.Join(fixed, ", "))
+	buildConfigs := c.openshiftClient.BuildConfigs(bc.Namespace)
+	build, err := buildConfigs.Instantiate(&buildapi.BuildRequest{
+		ObjectMeta:  kapi.ObjectMeta{Name: bc.Name, Namespace: bc.Namespace},
+		TriggeredBy: []buildapi.BuildTriggerCause{{Message: message}},
+	})
+	if err != nil {
+		log.Printf("Error rebuilding %s: %s", name, err)
+		return
+	}
+	log.Printf("Rebuilding %s as build %s: %s", name, build.Name, message)
+
+	record := &annotations.Rebuild{Triggered: now, Build: build.Name, Image: image.Name, Base: base.Name, Fixed: fixed}
+	err = retryOnConflict("build config "+name, func() error {
+		current, err := buildConfigs.Get(bc.Name)
+		if err != nil {
+			return err
+		}
+		current.Annotations = annotations.Merge(current.Annotations, map[string]string{annotations.RebuildKey: record.ToJSON()})
+		_, err = buildConfigs.Update(current)
+		return err
+	})
+	if err != nil {
+		log.Printf("Error recording rebuild of %s: %s", name, err)
+	}
+
+	ref := &kapi.ObjectReference{Kind: "BuildConfig", Namespace: bc.Namespace, Name: bc.Name, UID: bc.UID}
+	c.events.event(ref, kapi.EventTypeNormal, reasonRebuildTriggered, message)
+	c.imageEvent(config, image, kapi.EventTypeNormal, reasonRebuildTriggered,
+		fmt.Sprintf("Rebuilding with build config %s: %s", name, message))
+}
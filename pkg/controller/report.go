@@ -0,0 +1,70 @@
+package controller
+
+import (
+	"log"
+
+	kapi "k8s.io/kubernetes/pkg/api"
+	kerrors "k8s.io/kubernetes/pkg/api/errors"
+
+	imageapi "github.com/openshift/origin/pkg/image/api"
+
+	"github.com/RedHatInsights/insights-ocp-controller/pkg/annotations"
+	"github.com/RedHatInsights/insights-ocp-controller/pkg/report"
+)
+
+// refreshProjectReports rewrites the summary ConfigMap of every project
+// using a freshly scanned image
+func (c *Controller) refreshProjectReports(config *scanConfig, image *imageapi.Image) {
+	for _, namespace := range config.workloads.namespacesFor(image) {
+		if err := c.writeProjectReport(config, namespace); err != nil {
+			log.Printf("Error writing scan summary of project %s: %s", namespace, err)
+		}
+	}
+}
+
+// projectReport collects the scan results of every image used in a project
+// from the images' annotations
+func (c *Controller) projectReport(config *scanConfig, namespace string) *report.ProjectReport {
+	entries := []report.ImageEntry{}
+	for _, digest := range config.workloads.imagesIn(namespace) {
+		image, err := c.openshiftClient.Images().Get(digest)
+		if err != nil {
+			// Docker image IDs of pods are not image names
+			if !kerrors.IsNotFound(err) {
+				log.Printf("Error getting image %s: %s", digest, err)
+			}
+			continue
+		}
+		entries = append(entries, report.ImageEntry{
+			Image:        image.Name,
+			Phase:        annotations.ParseStatus(image.Annotations).Phase,
+			ImageSummary: annotations.ParseSummaries(image.Annotations[annotations.SummaryKey])[image.Name],
+		})
+	}
+	return report.NewProjectReport(namespace, entries)
+}
+
+func (c *Controller) writeProjectReport(config *scanConfig, namespace string) error {
+	data := c.projectReport(config, namespace).ToJSON()
+	configMaps := c.kubeClient.ConfigMaps(namespace)
+
+	return retryOnConflict("scan summary of project "+namespace, func() error {
+		configMap, err := configMaps.Get(report.ConfigMapName)
+		if kerrors.IsNotFound(err) {
+			_, err = configMaps.Create(&kapi.ConfigMap{
+				ObjectMeta: kapi.ObjectMeta{Name: report.ConfigMapName, Namespace: namespace},
+				Data:       map[string]string{report.ConfigMapKey: data},
+			})
+			return err
+		}
+		if err != nil {
+			return err
+		}
+		if configMap.Data == nil {
+			configMap.Data = make(map[string]string)
+		}
+		configMap.Data[report.ConfigMapKey] = data
+		_, err = configMaps.Update(configMap)
+		return err
+	})
+}
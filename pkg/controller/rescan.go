@@ -0,0 +1,50 @@
+package controller
+
+import (
+	"sort"
+
+	imageapi "github.com/openshift/origin/pkg/image/api"
+
+	"github.com/RedHatInsights/insights-ocp-controller/pkg/annotations"
+)
+
+// scannedVersion returns the egg version recorded on the image, if any
+func scannedVersion(image *imageapi.Image) string {
+	return image.Annotations[annotations.ScannerVersionKey]
+}
+
+// upToDate reports whether the annotations of an image were produced with
+// the current egg and configuration
+func (config *scanConfig) upToDate(image *imageapi.Image) bool {
+	return scannedVersion(image) == config.eggVersion && image.Annotations[annotations.ConfigHashKey] == config.hash
+}
+
+// byScanPriority orders images so that those never scanned with the current
+// version come first, highest workload risk first
+type byScanPriority struct {
+	images  []imageapi.Image
+	version string
+	scores  map[string]int
+}
+
+func (p byScanPriority) Len() int      { return len(p.images) }
+func (p byScanPriority) Swap(i, j int) { p.images[i], p.images[j] = p.images[j], p.images[i] }
+func (p byScanPriority) Less(i, j int) bool {
+	iOutdated := scannedVersion(&p.images[i]) != p.version
+	jOutdated := scannedVersion(&p.images[j]) != p.version
+	if iOutdated != jOutdated {
+		return iOutdated
+	}
+	return p.scores[p.images[i].Name] > p.scores[p.images[j].Name]
+}
+
+// sortByScanPriority sorts images in the order they should be scanned, scoring
+// each by how it is used and the findings of its previous scan
+func (config *scanConfig) sortByScanPriority(images []imageapi.Image) {
+	scores := make(map[string]int, len(images))
+	for i := range images {
+		image := &images[i]
+		scores[image.Name] = config.workloads.riskOf(image, annotations.ParseFindingCounts(image.Annotations)).Score
+	}
+	sort.Stable(byScanPriority{images: images, version: config.eggVersion, scores: scores})
+}
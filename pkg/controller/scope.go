@@ -0,0 +1,98 @@
+package controller
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+
+	imageapi "github.com/openshift/origin/pkg/image/api"
+
+	"github.com/RedHatInsights/insights-ocp-controller/pkg/annotations"
+	"github.com/RedHatInsights/insights-ocp-controller/pkg/scope"
+)
+
+const (
+	defaultScopeConfigMap = "insights-scope"
+	scopeConfigMapKey     = "scope.yaml"
+)
+
+// loadScope reads the include and exclude rules from the ConfigMap named by
+// SCOPE_CONFIGMAP in the controller's namespace. Invalid rules are reported
+// and the previously loaded ones kept.
+func (c *Controller) loadScope(previous *scope.Rules) *scope.Rules {
+	data, found, err := c.configMapData("SCOPE_CONFIGMAP", defaultScopeConfigMap, scopeConfigMapKey)
+	if err != nil {
+		log.Println(err)
+		return previous
+	}
+	if !found {
+		return nil
+	}
+	rules, err := scope.Parse([]byte(data))
+	if err != nil {
+		log.Printf("Error in scope ConfigMap, keeping previous rules: %s", err)
+		return previous
+	}
+	return rules
+}
+
+// skipReason returns why an image is out of the scan scope, or an empty
+// reason when it should be scanned
+func (c *Controller) skipReason(config *scanConfig, image *imageapi.Image) string {
+	ref, err := imageapi.ParseDockerImageReference(string(image.DockerImageReference))
+	if err != nil {
+		return ""
+	}
+
+	var namespaceLabels map[string]string
+	if len(ref.Namespace) > 0 {
+		ns, err := c.kubeClient.Namespaces().Get(ref.Namespace)
+		if err == nil {
+			if ns.Annotations[annotations.SkipScanKey] == "true" {
+				return scope.ReasonOptedOut
+			}
+			namespaceLabels = ns.Labels
+		}
+		stream, err := c.openshiftClient.ImageStreams(ref.Namespace).Get(ref.Name)
+		if err == nil && stream.Annotations[annotations.SkipScanKey] == "true" {
+			return scope.ReasonOptedOut
+		}
+	}
+	return config.scope.Skip(ref.Namespace, namespaceLabels, imageRepositories(string(image.DockerImageReference)))
+}
+
+// skipCounter counts the images skipped for each reason
+type skipCounter struct {
+	counts map[string]int
+	lock   sync.Mutex
+}
+
+func newSkipCounter() *skipCounter {
+	return &skipCounter{counts: make(map[string]int)}
+}
+
+func (s *skipCounter) add(reason string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.counts[reason]++
+}
+
+// report logs and resets the counts
+func (s *skipCounter) report() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if len(s.counts) == 0 {
+		return
+	}
+	reasons := make([]string, 0, len(s.counts))
+	total := 0
+	for reason, count := range s.counts {
+		reasons = append(reasons, fmt.Sprintf("%s=%d", reason, count))
+		total += count
+	}
+	sort.Strings(reasons)
+	log.Printf("Skipped %d images out of scope: %s", total, strings.Join(reasons, ", "))
+	s.counts = make(map[string]int)
+}
@@ -0,0 +1,66 @@
+package controller
+
+import (
+	"log"
+	"os"
+
+	kapi "k8s.io/kubernetes/pkg/api"
+
+	imageapi "github.com/openshift/origin/pkg/image/api"
+)
+
+// Values of SCAN_SELECTION
+const (
+	// selectAll scans every image of the cluster
+	selectAll = "all"
+	// selectInUse scans the images of running pods and current tags
+	selectInUse = "in-use"
+	// selectTagged scans the images current tags point at
+	selectTagged = "tagged"
+)
+
+// scanSelection returns which images SCAN_SELECTION asks to scan, all of
+// them when it is not set or unknown
+func scanSelection() string {
+	switch selection := os.Getenv("SCAN_SELECTION"); selection {
+	case selectInUse, selectTagged:
+		return selection
+	case "", selectAll:
+		return selectAll
+	default:
+		log.Printf("Unknown SCAN_SELECTION %q, scanning all images", selection)
+		return selectAll
+	}
+}
+
+// selectImages keeps the images the scan selection asks for
+func (config *scanConfig) selectImages(images []imageapi.Image) []imageapi.Image {
+	selection := scanSelection()
+	if selection == selectAll {
+		return images
+	}
+	selected := images[:0]
+	for i := range images {
+		image := &images[i]
+		if config.workloads.tagged(image) || (selection == selectInUse && config.workloads.running(image)) {
+			selected = append(selected, *image)
+		}
+	}
+	log.Printf("Selected %d of %d images (%s)", len(selected), len(images), selection)
+	return selected
+}
+
+// tagged reports whether an image stream tag currently points at the image
+func (w *workloadIndex) tagged(image *imageapi.Image) bool {
+	return len(w.tagsFor(image)) > 0
+}
+
+// running reports whether a running pod uses the image
+func (w *workloadIndex) running(image *imageapi.Image) bool {
+	for _, pod := range w.podsFor(image) {
+		if pod.Status.Phase == kapi.PodRunning {
+			return true
+		}
+	}
+	return false
+}
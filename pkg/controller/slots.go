@@ -0,0 +1,43 @@
+package controller
+
+import "sync"
+
+// scanSlots limits how many scans run at once. Urgent scans get the next
+// free slot before any regular scan waiting for one.
+type scanSlots struct {
+	max    int
+	inUse  int
+	urgent int
+	cond   *sync.Cond
+}
+
+func newScanSlots(max int) *scanSlots {
+	if max < 1 {
+		max = 1
+	}
+	return &scanSlots{max: max, cond: sync.NewCond(&sync.Mutex{})}
+}
+
+// acquire blocks until a slot is free for a scan of the given urgency
+func (s *scanSlots) acquire(urgent bool) {
+	s.cond.L.Lock()
+	defer s.cond.L.Unlock()
+	if urgent {
+		s.urgent++
+	}
+	for s.inUse >= s.max || (!urgent && s.urgent > 0) {
+		s.cond.Wait()
+	}
+	if urgent {
+		s.urgent--
+	}
+	s.inUse++
+}
+
+// release frees a slot taken by acquire
+func (s *scanSlots) release() {
+	s.cond.L.Lock()
+	defer s.cond.L.Unlock()
+	s.inUse--
+	s.cond.Broadcast()
+}
@@ -0,0 +1,66 @@
+package controller
+
+import (
+	"log"
+	"time"
+
+	imageapi "github.com/openshift/origin/pkg/image/api"
+
+	"github.com/RedHatInsights/insights-goapi/common"
+
+	"github.com/RedHatInsights/insights-ocp-controller/pkg/suppress"
+)
+
+const (
+	defaultSuppressionConfigMap = "insights-suppressions"
+	suppressionConfigMapKey     = "suppressions.yaml"
+)
+
+// loadSuppressions reads the suppression rules from the ConfigMap named by
+// SUPPRESSION_CONFIGMAP in the controller's namespace. Invalid rules are
+// reported and the previously loaded ones kept.
+func (c *Controller) loadSuppressions(previous *suppress.Suppressions) *suppress.Suppressions {
+	data, found, err := c.configMapData("SUPPRESSION_CONFIGMAP", defaultSuppressionConfigMap, suppressionConfigMapKey)
+	if err != nil {
+		log.Println(err)
+		return previous
+	}
+	if !found {
+		return nil
+	}
+
+	suppressions, err := suppress.Parse([]byte(data))
+	if err != nil {
+		log.Printf("Error in suppression ConfigMap, keeping previous suppressions: %s", err)
+		return previous
+	}
+	return suppressions
+}
+
+// suppressFindings removes the suppressed and acknowledged findings from the
+// scan results of an image and returns them
+func (config *scanConfig) suppressFindings(imageRef string, res *common.ScanResponse) map[string]common.Report {
+	active, suppressed := config.suppressions.Apply(res.Reports, imageNamespace(imageRef), imageRepositories(imageRef), time.Now())
+	if len(suppressed) > 0 {
+		log.Printf("Suppressed %d findings of image %s", len(suppressed), imageRef)
+	}
+	res.Reports = active
+	return suppressed
+}
+
+// imageRepositories returns the repository of an image reference with and
+// without its registry
+func imageRepositories(imageRef string) []string {
+	ref, err := imageapi.ParseDockerImageReference(imageRef)
+	if err != nil {
+		return nil
+	}
+	repository := ref.Namespace + "/" + ref.Name
+	if len(ref.Namespace) == 0 {
+		repository = ref.Name
+	}
+	if len(ref.Registry) == 0 {
+		return []string{repository}
+	}
+	return []string{repository, ref.Registry + "/" + repository}
+}
@@ -0,0 +1,140 @@
+package controller
+
+import (
+	"log"
+	"sort"
+	"time"
+
+	kapi "k8s.io/kubernetes/pkg/api"
+
+	imageapi "github.com/openshift/origin/pkg/image/api"
+
+	"github.com/RedHatInsights/insights-ocp-controller/pkg/annotations"
+	"github.com/RedHatInsights/insights-ocp-controller/pkg/env"
+)
+
+// publishWork hands the images of a scan cycle to the scan workers in the
+// order they should be scanned. Images with a live claim are left alone.
+func (c *Controller) publishWork(config *scanConfig, images []imageapi.Image) {
+	now := time.Now()
+	timeout := workClaimTimeout()
+	published := 0
+	for i := range images {
+		image := &images[i]
+		if config.upToDate(image) {
+			continue
+		}
+		if work := annotations.ParseWork(image.Annotations); work != nil && !work.Claimable(now, timeout) {
+			continue
+		}
+		work := &annotations.Work{Priority: i, Requested: now}
+		if err := c.updateImageAnnotations(image.Name, map[string]string{annotations.WorkKey: work.ToJSON()}); err != nil {
+			log.Printf("Error handing out image %s: %s", image.Name, err)
+			continue
+		}
+		published++
+	}
+	log.Printf("Handed out %d images to the scan workers", published)
+}
+
+// workClaimTimeout is how long a claim lasts before the work is redone,
+// SCAN_WORK_CLAIM_SECONDS
+func workClaimTimeout() time.Duration {
+	return time.Duration(env.Int("SCAN_WORK_CLAIM_SECONDS", 3600)) * time.Second
+}
+
+// RunScanWorker claims and scans the images handed out by the leader every
+// SCAN_WORK_POLL_SECONDS. Without worker followers it returns at once.
+func (c *Controller) RunScanWorker() {
+	if c.leader == nil || !c.leader.Workers {
+		return
+	}
+	interval := time.Duration(env.Int("SCAN_WORK_POLL_SECONDS", 15)) * time.Second
+	timeout := workClaimTimeout()
+	for {
+		c.scanWork(timeout)
+		time.Sleep(interval)
+	}
+}
+
+// scanWork claims and scans the claimable images, highest priority first
+func (c *Controller) scanWork(timeout time.Duration) {
+	images, err := c.openshiftClient.Images().List(kapi.ListOptions{})
+	if err != nil {
+		log.Printf("Error listing images for scan work: %s", err)
+		return
+	}
+	now := time.Now()
+	work := byWorkPriority{priorities: make(map[string]int)}
+	for i := range images.Items {
+		image := &images.Items[i]
+		if w := annotations.ParseWork(image.Annotations); w != nil && w.Claimable(now, timeout) {
+			work.images = append(work.images, image)
+			work.priorities[image.Name] = w.Priority
+		}
+	}
+	sort.Stable(work)
+
+	for _, image := range work.images {
+		// Without scan jobs only images on this replica's node can be scanned
+		if c.jobs == nil && !c.imageExists(image.DockerImageMetadata.ID) {
+			continue
+		}
+		config := c.currentConfig()
+		if config == nil || time.Since(config.prepared) > timeout/4 {
+			if config = c.prepare(); config == nil {
+				return
+			}
+		}
+		c.slots.acquire(false)
+		if !c.claimWork(image.Name, timeout) {
+			c.slots.release()
+			continue
+		}
+		c.wait.Add(1)
+		go func(config *scanConfig, image *imageapi.Image) {
+			defer func() {
+				c.slots.release()
+				c.wait.Done()
+			}()
+			c.processImage(config, image, false)
+			if err := c.updateImageAnnotations(image.Name, map[string]string{annotations.WorkKey: ""}); err != nil {
+				log.Printf("Error completing the scan work of image %s: %s", image.Name, err)
+			}
+		}(config, image)
+	}
+}
+
+// byWorkPriority orders handed out images by the priority the leader gave them
+type byWorkPriority struct {
+	images     []*imageapi.Image
+	priorities map[string]int
+}
+
+func (w byWorkPriority) Len() int      { return len(w.images) }
+func (w byWorkPriority) Swap(i, j int) { w.images[i], w.images[j] = w.images[j], w.images[i] }
+func (w byWorkPriority) Less(i, j int) bool {
+	return w.priorities[w.images[i].Name] < w.priorities[w.images[j].Name]
+}
+
+// claimWork marks the work of an image as taken by this replica. It fails if
+// another replica claimed it first.
+func (c *Controller) claimWork(digest string, timeout time.Duration) bool {
+	image, err := c.openshiftClient.Images().Get(digest)
+	if err != nil {
+		return false
+	}
+	now := time.Now()
+	work := annotations.ParseWork(image.Annotations)
+	if work == nil || !work.Claimable(now, timeout) {
+		return false
+	}
+	work.ClaimedBy = c.leader.Identity
+	work.Claimed = now
+	image.Annotations = annotations.Merge(image.Annotations, map[string]string{annotations.WorkKey: work.ToJSON()})
+	if _, err = c.openshiftClient.Images().Update(image); err != nil {
+		return false
+	}
+	log.Printf("Claimed the scan of image %s", digest)
+	return true
+}
@@ -0,0 +1,328 @@
+package controller
+
+import (
+	"log"
+	"regexp"
+
+	kapi "k8s.io/kubernetes/pkg/api"
+	"k8s.io/kubernetes/pkg/labels"
+	"k8s.io/kubernetes/pkg/types"
+
+	imageapi "github.com/openshift/origin/pkg/image/api"
+	routeapi "github.com/openshift/origin/pkg/route/api"
+
+	"github.com/RedHatInsights/insights-ocp-controller/pkg/annotations"
+)
+
+var digestPattern = regexp.MustCompile(`sha256:[0-9a-f]{64}`)
+
+// workloadIndex maps image digests to the pods and deployment configs that
+// reference them and to the image stream tags currently pointing at them
+type workloadIndex struct {
+	pods              map[string][]*kapi.Pod
+	deploymentConfigs map[string][]*kapi.ObjectReference
+	imageStreamTags   map[string][]imageStreamTag
+	// namespaces maps each namespace to the digests used in it
+	namespaces map[string]map[string]bool
+	// routes maps pod UIDs to the routes exposing the pod
+	routes map[types.UID][]string
+}
+
+// imageStreamTag identifies the tag of an image stream
+type imageStreamTag struct {
+	namespace string
+	stream    string
+	tag       string
+}
+
+// buildWorkloadIndex lists the pods and deployment configs of all namespaces
+// and indexes them by the digests found in their image references
+func (c *Controller) buildWorkloadIndex() *workloadIndex {
+	index := &workloadIndex{
+		pods:              make(map[string][]*kapi.Pod),
+		deploymentConfigs: make(map[string][]*kapi.ObjectReference),
+		imageStreamTags:   make(map[string][]imageStreamTag),
+		namespaces:        make(map[string]map[string]bool),
+		routes:            make(map[types.UID][]string),
+	}
+
+	pods, err := c.kubeClient.Pods(kapi.NamespaceAll).List(kapi.ListOptions{})
+	if err != nil {
+		log.Printf("Error listing pods: %s", err)
+	} else {
+		for i := range pods.Items {
+			pod := &pods.Items[i]
+			for _, digest := range digests(podImageRefs(pod)) {
+				index.pods[digest] = append(index.pods[digest], pod)
+				index.use(pod.Namespace, digest)
+			}
+		}
+		c.indexRoutes(index, pods.Items)
+	}
+
+	dcs, err := c.openshiftClient.DeploymentConfigs(kapi.NamespaceAll).List(kapi.ListOptions{})
+	if err != nil {
+		log.Printf("Error listing deployment configs: %s", err)
+	} else {
+		for i := range dcs.Items {
+			dc := &dcs.Items[i]
+			if dc.Spec.Template == nil {
+				continue
+			}
+			refs := podSpecImageRefs(&dc.Spec.Template.Spec)
+			ref := &kapi.ObjectReference{
+				Kind:            "DeploymentConfig",
+				APIVersion:      "v1",
+				Namespace:       dc.Namespace,
+				Name:            dc.Name,
+				UID:             dc.UID,
+				ResourceVersion: dc.ResourceVersion,
+			}
+			for _, digest := range digests(refs) {
+				index.deploymentConfigs[digest] = append(index.deploymentConfigs[digest], ref)
+				index.use(dc.Namespace, digest)
+			}
+		}
+	}
+
+	streams, err := c.openshiftClient.ImageStreams(kapi.NamespaceAll).List(kapi.ListOptions{})
+	if err != nil {
+		log.Printf("Error listing image streams: %s", err)
+	} else {
+		for _, stream := range streams.Items {
+			for tag, history := range stream.Status.Tags {
+				// The first item is the image the tag currently points at
+				if len(history.Items) == 0 {
+					continue
+				}
+				digest := history.Items[0].Image
+				index.imageStreamTags[digest] = append(index.imageStreamTags[digest],
+					imageStreamTag{namespace: stream.Namespace, stream: stream.Name, tag: tag})
+				index.use(stream.Namespace, digest)
+			}
+		}
+	}
+
+	return index
+}
+
+// indexRoutes records which routes expose each pod through its services
+func (c *Controller) indexRoutes(index *workloadIndex, pods []kapi.Pod) {
+	services, err := c.kubeClient.Services(kapi.NamespaceAll).List(kapi.ListOptions{})
+	if err != nil {
+		log.Printf("Error listing services: %s", err)
+		return
+	}
+	routes, err := c.openshiftClient.Routes(kapi.NamespaceAll).List(kapi.ListOptions{})
+	if err != nil {
+		log.Printf("Error listing routes: %s", err)
+		return
+	}
+
+	selectors := make(map[string]labels.Selector)
+	for _, service := range services.Items {
+		if len(service.Spec.Selector) > 0 {
+			selectors[service.Namespace+"/"+service.Name] = labels.SelectorFromSet(service.Spec.Selector)
+		}
+	}
+	for _, route := range routes.Items {
+		backends := append([]routeapi.RouteTargetReference{route.Spec.To}, route.Spec.AlternateBackends...)
+		for _, backend := range backends {
+			selector, ok := selectors[route.Namespace+"/"+backend.Name]
+			if backend.Kind != "Service" || !ok {
+				continue
+			}
+			for i := range pods {
+				pod := &pods[i]
+				if pod.Namespace == route.Namespace && selector.Matches(labels.Set(pod.Labels)) {
+					index.routes[pod.UID] = append(index.routes[pod.UID], route.Namespace+"/"+route.Name)
+				}
+			}
+		}
+	}
+}
+
+// riskOf scores an image with the given finding counts by the pods running
+// it, the routes exposing them and their privileges
+func (w *workloadIndex) riskOf(image *imageapi.Image, counts map[string]int) *annotations.WorkloadRisk {
+	running := 0
+	privileged := false
+	routes := make(map[string]bool)
+	for _, pod := range w.podsFor(image) {
+		if pod.Status.Phase != kapi.PodRunning {
+			continue
+		}
+		running++
+		privileged = privileged || privilegedPod(pod)
+		for _, route := range w.routes[pod.UID] {
+			routes[route] = true
+		}
+	}
+	return annotations.NewWorkloadRisk(counts, running, len(routes), privileged)
+}
+
+// privilegedSCCs are the security context constraints granting more than the
+// restricted default
+var privilegedSCCs = map[string]bool{
+	"privileged":       true,
+	"anyuid":           true,
+	"hostaccess":       true,
+	"hostmount-anyuid": true,
+	"hostnetwork":      true,
+}
+
+// privilegedPod reports whether a pod was admitted with a privileged SCC or
+// asks for privileged containers or host namespaces
+func privilegedPod(pod *kapi.Pod) bool {
+	if privilegedSCCs[pod.Annotations["openshift.io/scc"]] {
+		return true
+	}
+	if sc := pod.Spec.SecurityContext; sc != nil && (sc.HostNetwork || sc.HostPID || sc.HostIPC) {
+		return true
+	}
+	for _, container := range pod.Spec.Containers {
+		if sc := container.SecurityContext; sc != nil && sc.Privileged != nil && *sc.Privileged {
+			return true
+		}
+	}
+	return false
+}
+
+// use records that a digest is used in a namespace
+func (w *workloadIndex) use(namespace string, digest string) {
+	if w.namespaces[namespace] == nil {
+		w.namespaces[namespace] = make(map[string]bool)
+	}
+	w.namespaces[namespace][digest] = true
+}
+
+// namespacesFor returns the namespaces using an image
+func (w *workloadIndex) namespacesFor(image *imageapi.Image) []string {
+	if w == nil {
+		return nil
+	}
+	result := []string{}
+	for namespace, used := range w.namespaces {
+		for _, key := range imageKeys(image) {
+			if used[key] {
+				result = append(result, namespace)
+				break
+			}
+		}
+	}
+	return result
+}
+
+// imagesIn returns the digests used in a namespace
+func (w *workloadIndex) imagesIn(namespace string) []string {
+	if w == nil {
+		return nil
+	}
+	result := []string{}
+	for digest := range w.namespaces[namespace] {
+		result = append(result, digest)
+	}
+	return result
+}
+
+// tagsFor returns the image stream tags pointing at an image
+func (w *workloadIndex) tagsFor(image *imageapi.Image) []imageStreamTag {
+	if w == nil {
+		return nil
+	}
+	return w.imageStreamTags[image.Name]
+}
+
+// podsFor returns the pods running an image, at most once each
+func (w *workloadIndex) podsFor(image *imageapi.Image) []*kapi.Pod {
+	if w == nil {
+		return nil
+	}
+	seen := make(map[string]bool)
+	result := []*kapi.Pod{}
+	for _, key := range imageKeys(image) {
+		for _, pod := range w.pods[key] {
+			if !seen[string(pod.UID)] {
+				seen[string(pod.UID)] = true
+				result = append(result, pod)
+			}
+		}
+	}
+	return result
+}
+
+// referencesFor returns references to the pods and deployment configs using
+// an image, at most once each
+func (w *workloadIndex) referencesFor(image *imageapi.Image) []*kapi.ObjectReference {
+	if w == nil {
+		return nil
+	}
+	result := []*kapi.ObjectReference{}
+	for _, pod := range w.podsFor(image) {
+		result = append(result, &kapi.ObjectReference{
+			Kind:            "Pod",
+			APIVersion:      "v1",
+			Namespace:       pod.Namespace,
+			Name:            pod.Name,
+			UID:             pod.UID,
+			ResourceVersion: pod.ResourceVersion,
+		})
+	}
+	seen := make(map[string]bool)
+	for _, key := range imageKeys(image) {
+		for _, ref := range w.deploymentConfigs[key] {
+			if !seen[string(ref.UID)] {
+				seen[string(ref.UID)] = true
+				result = append(result, ref)
+			}
+		}
+	}
+	return result
+}
+
+// imageKeys returns the digests an image may be referenced by: its manifest
+// digest and its docker image ID
+func imageKeys(image *imageapi.Image) []string {
+	keys := []string{image.Name}
+	if id := image.DockerImageMetadata.ID; len(id) > 0 && id != image.Name {
+		if digestPattern.MatchString(id) {
+			keys = append(keys, id)
+		} else {
+			keys = append(keys, "sha256:"+id)
+		}
+	}
+	return keys
+}
+
+// podImageRefs returns the image references of a pod's containers, both as
+// specified and as resolved by the node
+func podImageRefs(pod *kapi.Pod) []string {
+	refs := podSpecImageRefs(&pod.Spec)
+	for _, status := range pod.Status.ContainerStatuses {
+		refs = append(refs, status.Image, status.ImageID)
+	}
+	return refs
+}
+
+func podSpecImageRefs(spec *kapi.PodSpec) []string {
+	refs := []string{}
+	for _, container := range spec.Containers {
+		refs = append(refs, container.Image)
+	}
+	return refs
+}
+
+// digests returns the distinct sha256 digests found in refs
+func digests(refs []string) []string {
+	seen := make(map[string]bool)
+	result := []string{}
+	for _, ref := range refs {
+		for _, digest := range digestPattern.FindAllString(ref, -1) {
+			if !seen[digest] {
+				seen[digest] = true
+				result = append(result, digest)
+			}
+		}
+	}
+	return result
+}
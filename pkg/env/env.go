@@ -0,0 +1,16 @@
+package env
+
+import (
+	"os"
+	"strconv"
+)
+
+// Int returns the integer value of the environment variable name, or def
+// when it is not set or not a number
+func Int(name string, def int) int {
+	value, err := strconv.Atoi(os.Getenv(name))
+	if err != nil {
+		return def
+	}
+	return value
+}
@@ -0,0 +1,87 @@
+package leader
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// RecordKey is the annotation of the lock object holding the leader record,
+// as used by upstream controllers
+const RecordKey = "control-plane.alpha.kubernetes.io/leader"
+
+// Record describes who holds the lock
+type Record struct {
+	HolderIdentity       string    `json:"holderIdentity"`
+	LeaseDurationSeconds int       `json:"leaseDurationSeconds"`
+	AcquireTime          time.Time `json:"acquireTime"`
+	RenewTime            time.Time `json:"renewTime"`
+	LeaderTransitions    int       `json:"leaderTransitions"`
+}
+
+// Parse reads a leader record, nil for an empty or invalid value
+func Parse(value string) *Record {
+	if len(value) == 0 {
+		return nil
+	}
+	var record Record
+	if err := json.Unmarshal([]byte(value), &record); err != nil {
+		return nil
+	}
+	return &record
+}
+
+// ToJSON - return json version of the record
+func (r *Record) ToJSON() string {
+	str, err := json.Marshal(r)
+	if err != nil {
+		return "{}"
+	}
+	return string(str)
+}
+
+// Elector decides when a candidate may take or keep the lock. Leases of other
+// holders are timed from when their record was last seen changing, so clock
+// skew between replicas does not matter.
+type Elector struct {
+	Identity string
+	Lease    time.Duration
+
+	observed     *Record
+	observedTime time.Time
+}
+
+// Next returns the record to write to take or renew the lock given the
+// current record, or nil while another holder's lease is valid
+func (e *Elector) Next(current *Record, now time.Time) *Record {
+	if current != nil && (e.observed == nil || *current != *e.observed) {
+		e.observed = current
+		e.observedTime = now
+	}
+	if current != nil && current.HolderIdentity != e.Identity &&
+		len(current.HolderIdentity) > 0 && now.Before(e.observedTime.Add(e.Lease)) {
+		return nil
+	}
+
+	next := &Record{
+		HolderIdentity:       e.Identity,
+		LeaseDurationSeconds: int(e.Lease / time.Second),
+		AcquireTime:          now,
+		RenewTime:            now,
+	}
+	if current != nil {
+		next.LeaderTransitions = current.LeaderTransitions
+		if current.HolderIdentity == e.Identity {
+			next.AcquireTime = current.AcquireTime
+		} else {
+			next.LeaderTransitions++
+		}
+	}
+	return next
+}
+
+// Observe records the record written by this candidate, so that its own
+// renewals are not mistaken for another holder's
+func (e *Elector) Observe(record *Record, now time.Time) {
+	e.observed = record
+	e.observedTime = now
+}
@@ -0,0 +1,37 @@
+package leader
+
+import (
+	"testing"
+	"time"
+)
+
+func TestElection(t *testing.T) {
+	now := time.Now()
+	a := &Elector{Identity: "a", Lease: 15 * time.Second}
+	b := &Elector{Identity: "b", Lease: 15 * time.Second}
+
+	record := a.Next(nil, now)
+	if record == nil || record.HolderIdentity != "a" {
+		t.Fatalf("Expected a to take a free lock, got %+v", record)
+	}
+	a.Observe(record, now)
+
+	if next := b.Next(Parse(record.ToJSON()), now.Add(time.Second)); next != nil {
+		t.Fatalf("Expected b to wait for a's lease, got %+v", next)
+	}
+
+	renewed := a.Next(record, now.Add(5*time.Second))
+	if renewed == nil || !renewed.AcquireTime.Equal(record.AcquireTime) || renewed.LeaderTransitions != 0 {
+		t.Fatalf("Expected a to renew its lease, got %+v", renewed)
+	}
+	a.Observe(renewed, now.Add(5*time.Second))
+
+	// b saw the renewal change the record, so the lease restarts from there
+	if next := b.Next(renewed, now.Add(10*time.Second)); next != nil {
+		t.Fatalf("Expected b to wait for the renewed lease, got %+v", next)
+	}
+	next := b.Next(renewed, now.Add(26*time.Second))
+	if next == nil || next.HolderIdentity != "b" || next.LeaderTransitions != 1 {
+		t.Fatalf("Expected b to take the expired lock, got %+v", next)
+	}
+}
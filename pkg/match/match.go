@@ -0,0 +1,55 @@
+package match
+
+import (
+	"fmt"
+	"path"
+)
+
+// Target matches images by the namespace they were pushed to and their
+// repositories. Every criterion set must match; a target without criteria
+// matches every image.
+type Target struct {
+	// Namespaces the image may have been pushed to
+	Namespaces []string `json:"namespaces,omitempty"`
+	// Repositories are patterns matched against the image repository with
+	// and without its registry, e.g. "openshift/*"
+	Repositories []string `json:"repositories,omitempty"`
+}
+
+// Validate checks the repository patterns
+func (t *Target) Validate() error {
+	for _, pattern := range t.Repositories {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("Invalid repository pattern %q", pattern)
+		}
+	}
+	return nil
+}
+
+// Matches returns whether an image pushed to namespace and known under
+// repositories is a target
+func (t *Target) Matches(namespace string, repositories []string) bool {
+	if len(t.Namespaces) > 0 && !contains(t.Namespaces, namespace) {
+		return false
+	}
+	if len(t.Repositories) == 0 {
+		return true
+	}
+	for _, pattern := range t.Repositories {
+		for _, repository := range repositories {
+			if matched, _ := path.Match(pattern, repository); matched {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,38 @@
+package match
+
+import (
+	"testing"
+)
+
+func TestEmptyTargetMatchesEverything(t *testing.T) {
+	var target Target
+	if !target.Matches("myproject", nil) {
+		t.Fatal("Expected a target without criteria to match")
+	}
+}
+
+func TestMatches(t *testing.T) {
+	target := Target{
+		Namespaces:   []string{"myproject", "other"},
+		Repositories: []string{"myproject/*"},
+	}
+	repositories := []string{"registry:5000/myproject/app", "myproject/app"}
+	if !target.Matches("myproject", repositories) {
+		t.Fatal("Expected the namespace and repository to match")
+	}
+	if target.Matches("third", repositories) {
+		t.Fatal("Expected another namespace not to match")
+	}
+	if target.Matches("other", []string{"other/app"}) {
+		t.Fatal("Expected another repository not to match")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	if err := (&Target{Repositories: []string{"[a-"}}).Validate(); err == nil {
+		t.Fatal("Expected an invalid pattern to be refused")
+	}
+	if err := (&Target{Repositories: []string{"openshift/*"}}).Validate(); err != nil {
+		t.Fatal(err)
+	}
+}
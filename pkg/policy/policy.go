@@ -0,0 +1,187 @@
+package policy
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/ghodss/yaml"
+	"k8s.io/kubernetes/pkg/labels"
+
+	"github.com/RedHatInsights/insights-goapi/common"
+
+	"github.com/RedHatInsights/insights-ocp-controller/pkg/annotations"
+)
+
+// Threshold is the largest number of findings of a severity an image may
+// have, optionally only counting findings of one category
+type Threshold struct {
+	Severity string `json:"severity"`
+	Category string `json:"category,omitempty"`
+	Max      int    `json:"max"`
+}
+
+// Enforcement is what the admission webhook does with images that are not
+// compliant
+type Enforcement string
+
+const (
+	// EnforceAllow admits non compliant images
+	EnforceAllow Enforcement = "allow"
+	// EnforceWarn admits non compliant images with a warning
+	EnforceWarn Enforcement = "warn"
+	// EnforceDeny rejects non compliant images
+	EnforceDeny Enforcement = "deny"
+)
+
+// Policy decides whether the findings of an image are compliant
+type Policy struct {
+	Name string `json:"name"`
+	// NamespaceSelector is a label selector choosing the namespaces the
+	// policy applies to. An empty selector matches every namespace.
+	NamespaceSelector string      `json:"namespaceSelector,omitempty"`
+	Thresholds        []Threshold `json:"thresholds,omitempty"`
+	// FailRules are rule IDs that make an image non compliant whenever hit
+	FailRules []string `json:"failRules,omitempty"`
+	// Enforcement applies to workloads in the selected namespaces, allow
+	// when not set
+	Enforcement Enforcement `json:"enforcement,omitempty"`
+
+	selector labels.Selector
+}
+
+// Policies is the list of policies in the order they are matched
+type Policies struct {
+	Policies []Policy `json:"policies"`
+}
+
+// Violation is a reason an image is not compliant
+type Violation struct {
+	Rule     string `json:"rule,omitempty"`
+	Severity string `json:"severity,omitempty"`
+	Category string `json:"category,omitempty"`
+	Count    int    `json:"count,omitempty"`
+	Max      int    `json:"max,omitempty"`
+	Message  string `json:"message"`
+}
+
+// Verdict is the outcome of evaluating a policy
+type Verdict struct {
+	Policy     string      `json:"policy"`
+	Compliant  bool        `json:"compliant"`
+	Violations []Violation `json:"violations,omitempty"`
+}
+
+// Parse reads policies from YAML or JSON
+func Parse(data []byte) (*Policies, error) {
+	var policies Policies
+	if err := yaml.Unmarshal(data, &policies); err != nil {
+		return nil, fmt.Errorf("Unable to parse policies: %v", err)
+	}
+	for i := range policies.Policies {
+		p := &policies.Policies[i]
+		if len(p.Name) == 0 {
+			return nil, fmt.Errorf("Policy %d has no name", i)
+		}
+		selector, err := labels.Parse(p.NamespaceSelector)
+		if err != nil {
+			return nil, fmt.Errorf("Policy %s has an invalid namespace selector: %v", p.Name, err)
+		}
+		p.selector = selector
+		switch p.Enforcement {
+		case "":
+			p.Enforcement = EnforceAllow
+		case EnforceAllow, EnforceWarn, EnforceDeny:
+		default:
+			return nil, fmt.Errorf("Policy %s has an unknown enforcement %q", p.Name, p.Enforcement)
+		}
+		for _, t := range p.Thresholds {
+			if annotations.SeverityIndex(strings.ToLower(t.Severity)) < 0 {
+				return nil, fmt.Errorf("Policy %s has an unknown severity %q", p.Name, t.Severity)
+			}
+		}
+	}
+	return &policies, nil
+}
+
+// For returns the first policy whose selector matches the namespace labels,
+// or nil when none does
+func (ps *Policies) For(namespaceLabels map[string]string) *Policy {
+	if ps == nil {
+		return nil
+	}
+	for i := range ps.Policies {
+		if ps.Policies[i].selector.Matches(labels.Set(namespaceLabels)) {
+			return &ps.Policies[i]
+		}
+	}
+	return nil
+}
+
+// EnforcementOf returns the enforcement of the policy, allow without one
+func (p *Policy) EnforcementOf() Enforcement {
+	if p == nil {
+		return EnforceAllow
+	}
+	return p.Enforcement
+}
+
+// Evaluate decides whether reports comply with the policy, labelling their
+// severities with mapping. Without a policy every image is compliant.
+func (p *Policy) Evaluate(reports map[string]common.Report, mapping *annotations.Mapping) *Verdict {
+	if p == nil {
+		return &Verdict{Compliant: true}
+	}
+	verdict := &Verdict{Policy: p.Name}
+
+	// Walk the rules in a stable order so the violations are too
+	keys := make([]string, 0, len(reports))
+	for key := range reports {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	failRules := make(map[string]bool, len(p.FailRules))
+	for _, rule := range p.FailRules {
+		failRules[rule] = true
+	}
+	for _, key := range keys {
+		if failRules[key] {
+			verdict.Violations = append(verdict.Violations, Violation{
+				Rule:    key,
+				Message: fmt.Sprintf("rule %s is not allowed", key),
+			})
+		}
+	}
+
+	for _, t := range p.Thresholds {
+		count := 0
+		for _, key := range keys {
+			report := reports[key]
+			if mapping.Severity(report) != strings.ToLower(t.Severity) {
+				continue
+			}
+			if len(t.Category) > 0 && !strings.EqualFold(report.Category, t.Category) {
+				continue
+			}
+			count++
+		}
+		if count > t.Max {
+			category := t.Category
+			if len(category) == 0 {
+				category = "any"
+			}
+			verdict.Violations = append(verdict.Violations, Violation{
+				Severity: strings.ToLower(t.Severity),
+				Category: t.Category,
+				Count:    count,
+				Max:      t.Max,
+				Message: fmt.Sprintf("%d %s findings in category %s exceed the limit of %d",
+					count, strings.ToLower(t.Severity), category, t.Max),
+			})
+		}
+	}
+
+	verdict.Compliant = len(verdict.Violations) == 0
+	return verdict
+}
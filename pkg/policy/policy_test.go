@@ -0,0 +1,85 @@
+package policy
+
+import (
+	"io/ioutil"
+	"testing"
+
+	"github.com/RedHatInsights/insights-goapi/common"
+
+	"github.com/RedHatInsights/insights-ocp-controller/pkg/annotations"
+)
+
+func loadPolicies(t *testing.T) *Policies {
+	data, err := ioutil.ReadFile("testdata/policy.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	policies, err := Parse(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return policies
+}
+
+func TestPolicySelection(t *testing.T) {
+	policies := loadPolicies(t)
+	if p := policies.For(map[string]string{"env": "production"}); p == nil || p.Name != "production" {
+		t.Fatalf("Expected production policy, got %v", p)
+	}
+	if p := policies.For(map[string]string{"env": "production"}); p.EnforcementOf() != EnforceDeny {
+		t.Fatalf("Expected production policy to deny, got %q", p.EnforcementOf())
+	}
+	if p := policies.For(map[string]string{"env": "dev"}); p == nil || p.Name != "default" || p.EnforcementOf() != EnforceAllow {
+		t.Fatalf("Expected default policy, got %v", p)
+	}
+	if p := (&Policies{}).For(nil); p != nil {
+		t.Fatalf("Expected no policy, got %v", p)
+	}
+}
+
+func TestPolicyEvaluate(t *testing.T) {
+	policies := loadPolicies(t)
+	reports := map[string]common.Report{
+		"CVE_2017_5715_CPU_VIRT|VIRT_CVE_2017_5715_CPU_3_ONLYKERNEL": {Severity: "WARN", Category: "Security"},
+		"HTTPOXY|HTTPOXY_VULNERABLE":                                 {Severity: "ERROR", Category: "Security"},
+		"OPENSSL_HEARTBLEED|HEARTBLEED":                              {Severity: "ERROR", Category: "Security"},
+		"SELINUX_DISABLED|SELINUX_DISABLED":                          {Severity: "CRITICAL", Category: "Stability"},
+	}
+
+	verdict := policies.For(map[string]string{"env": "production"}).Evaluate(reports, annotations.DefaultMapping())
+	if verdict.Compliant {
+		t.Fatalf("Expected non compliant verdict")
+	}
+	if verdict.Policy != "production" || len(verdict.Violations) != 3 {
+		t.Fatalf("Unexpected verdict %+v", verdict)
+	}
+	if verdict.Violations[0].Rule != "CVE_2017_5715_CPU_VIRT|VIRT_CVE_2017_5715_CPU_3_ONLYKERNEL" {
+		t.Fatalf("Expected failing rule first, got %+v", verdict.Violations[0])
+	}
+
+	// The default policy only limits critical security findings
+	verdict = policies.For(nil).Evaluate(reports, annotations.DefaultMapping())
+	if !verdict.Compliant || verdict.Policy != "default" {
+		t.Fatalf("Unexpected verdict %+v", verdict)
+	}
+}
+
+func TestNoPolicyIsCompliant(t *testing.T) {
+	var p *Policy
+	verdict := p.Evaluate(map[string]common.Report{"A|B": {Severity: "CRITICAL"}}, annotations.DefaultMapping())
+	if !verdict.Compliant {
+		t.Fatalf("Expected compliant verdict without a policy")
+	}
+}
+
+func TestParseRejectsInvalidPolicies(t *testing.T) {
+	if _, err := Parse([]byte("policies:\n- name: bad\n  namespaceSelector: 'env in (a'\n")); err == nil {
+		t.Fatalf("Expected invalid selector to be rejected")
+	}
+	if _, err := Parse([]byte("policies:\n- name: bad\n  thresholds:\n  - severity: urgent\n")); err == nil {
+		t.Fatalf("Expected unknown severity to be rejected")
+	}
+	if _, err := Parse([]byte("policies:\n- thresholds: []\n")); err == nil {
+		t.Fatalf("Expected unnamed policy to be rejected")
+	}
+}
@@ -0,0 +1,73 @@
+package rebuild
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// IsBase reports whether an image with the base layers is a base image of an
+// image with layers, that is its layers are a strict prefix of them
+func IsBase(base []string, layers []string) bool {
+	if len(base) == 0 || len(base) >= len(layers) {
+		return false
+	}
+	for i := range base {
+		if base[i] != layers[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// Fixed returns the rules a dependent image shares with its old base image
+// that the new base image no longer hits
+func Fixed(oldBase []string, newBase []string, dependent []string) []string {
+	inOld := make(map[string]bool, len(oldBase))
+	for _, rule := range oldBase {
+		inOld[rule] = true
+	}
+	inNew := make(map[string]bool, len(newBase))
+	for _, rule := range newBase {
+		inNew[rule] = true
+	}
+	fixed := []string{}
+	for _, rule := range dependent {
+		if inOld[rule] && !inNew[rule] {
+			fixed = append(fixed, rule)
+		}
+	}
+	sort.Strings(fixed)
+	return fixed
+}
+
+// Limiter allows at most max rebuilds within any window
+type Limiter struct {
+	max    int
+	window time.Duration
+	times  []time.Time
+	lock   sync.Mutex
+}
+
+// NewLimiter creates a limiter of max rebuilds per window
+func NewLimiter(max int, window time.Duration) *Limiter {
+	return &Limiter{max: max, window: window}
+}
+
+// Allow records a rebuild at now and returns true if it is within the limit
+func (l *Limiter) Allow(now time.Time) bool {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	recent := l.times[:0]
+	for _, t := range l.times {
+		if now.Sub(t) < l.window {
+			recent = append(recent, t)
+		}
+	}
+	l.times = recent
+	if len(l.times) >= l.max {
+		return false
+	}
+	l.times = append(l.times, now)
+	return true
+}
@@ -0,0 +1,44 @@
+package rebuild
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestIsBase(t *testing.T) {
+	base := []string{"sha256:a", "sha256:b"}
+	if !IsBase(base, []string{"sha256:a", "sha256:b", "sha256:c"}) {
+		t.Fatal("Expected a layer prefix to be a base")
+	}
+	if IsBase(base, base) {
+		t.Fatal("An image is not its own base")
+	}
+	if IsBase(base, []string{"sha256:a", "sha256:x", "sha256:c"}) {
+		t.Fatal("Expected diverging layers not to be a base")
+	}
+	if IsBase(nil, base) {
+		t.Fatal("An image without layers is no base")
+	}
+}
+
+func TestFixed(t *testing.T) {
+	fixed := Fixed([]string{"A", "B", "C"}, []string{"C"}, []string{"D", "B", "A", "C"})
+	if !reflect.DeepEqual(fixed, []string{"A", "B"}) {
+		t.Fatalf("Unexpected fixed rules %v", fixed)
+	}
+}
+
+func TestLimiter(t *testing.T) {
+	limiter := NewLimiter(2, time.Hour)
+	now := time.Now()
+	if !limiter.Allow(now) || !limiter.Allow(now) {
+		t.Fatal("Expected the first two rebuilds allowed")
+	}
+	if limiter.Allow(now.Add(time.Minute)) {
+		t.Fatal("Expected a third rebuild within the hour refused")
+	}
+	if !limiter.Allow(now.Add(time.Hour)) {
+		t.Fatal("Expected rebuilds allowed again after the window")
+	}
+}
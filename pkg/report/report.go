@@ -0,0 +1,79 @@
+package report
+
+import (
+	"encoding/json"
+	"sort"
+	"time"
+
+	"github.com/RedHatInsights/insights-ocp-controller/pkg/annotations"
+)
+
+const (
+	// ConfigMapName is the name of the summary ConfigMap in every project
+	ConfigMapName = "insights-scan-summary"
+	// ConfigMapKey is the ConfigMap key holding the summary
+	ConfigMapKey = "summary.json"
+)
+
+// ImageEntry is the scan result of one image used in a project. The summary
+// is missing for images that have not been scanned successfully.
+type ImageEntry struct {
+	Image string `json:"image"`
+	// Phase is the scan status of the image
+	Phase annotations.Phase `json:"phase,omitempty"`
+	*annotations.ImageSummary
+}
+
+// ProjectReport summarizes the health of every image used in a project
+type ProjectReport struct {
+	Namespace string       `json:"namespace"`
+	Updated   time.Time    `json:"updated"`
+	Images    []ImageEntry `json:"images"`
+}
+
+// NewProjectReport creates the report of a namespace, listing non compliant
+// images first and then by decreasing severity of their findings
+func NewProjectReport(namespace string, images []ImageEntry) *ProjectReport {
+	sort.Sort(byHealth(images))
+	return &ProjectReport{
+		Namespace: namespace,
+		Updated:   time.Now(),
+		Images:    images,
+	}
+}
+
+// ToJSON - return json version of the report
+func (r *ProjectReport) ToJSON() string {
+	str, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return "{}"
+	}
+	return string(str)
+}
+
+type byHealth []ImageEntry
+
+func (b byHealth) Len() int      { return len(b) }
+func (b byHealth) Swap(i, j int) { b[i], b[j] = b[j], b[i] }
+func (b byHealth) Less(i, j int) bool {
+	si, sj := b[i].ImageSummary, b[j].ImageSummary
+	if (si == nil) != (sj == nil) {
+		return si != nil
+	}
+	if si != nil {
+		if si.Compliant != sj.Compliant {
+			return !si.Compliant
+		}
+		for _, counts := range [][2]int{
+			{si.Critical, sj.Critical},
+			{si.High, sj.High},
+			{si.Medium, sj.Medium},
+			{si.Low, sj.Low},
+		} {
+			if counts[0] != counts[1] {
+				return counts[0] > counts[1]
+			}
+		}
+	}
+	return b[i].Image < b[j].Image
+}
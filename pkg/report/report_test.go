@@ -0,0 +1,36 @@
+package report
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/RedHatInsights/insights-ocp-controller/pkg/annotations"
+)
+
+func TestProjectReportOrder(t *testing.T) {
+	report := NewProjectReport("myproject", []ImageEntry{
+		{Image: "sha256:unscanned", Phase: annotations.PhaseFailed},
+		{Image: "sha256:clean", Phase: annotations.PhaseSucceeded,
+			ImageSummary: &annotations.ImageSummary{Compliant: true, Low: 1}},
+		{Image: "sha256:high", Phase: annotations.PhaseSucceeded,
+			ImageSummary: &annotations.ImageSummary{Compliant: true, High: 2}},
+		{Image: "sha256:violating", Phase: annotations.PhaseSucceeded,
+			ImageSummary: &annotations.ImageSummary{Compliant: false, Medium: 1}},
+	})
+
+	order := []string{"sha256:violating", "sha256:high", "sha256:clean", "sha256:unscanned"}
+	for i, image := range order {
+		if report.Images[i].Image != image {
+			t.Fatalf("Expected %s at %d, got %+v", image, i, report.Images)
+		}
+	}
+
+	var decoded map[string]interface{}
+	if err := json.Unmarshal([]byte(report.ToJSON()), &decoded); err != nil {
+		t.Fatal(err)
+	}
+	first := decoded["images"].([]interface{})[0].(map[string]interface{})
+	if first["medium"] != float64(1) || first["compliant"] != false {
+		t.Fatalf("Summary fields not inlined in %v", first)
+	}
+}
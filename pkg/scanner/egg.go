@@ -0,0 +1,111 @@
+package scanner
+
+import (
+	"archive/zip"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+const (
+	DefaultEggLocation     = "/etc/insights-client/rpm.egg"
+	DefaultKeyringLocation = "/etc/insights-client/redhattools.pub.gpg"
+	gpgCmd                 = "/usr/bin/gpg"
+)
+
+// EggOptions holds the location of the insights-core egg, its detached
+// signature and the keyring the signature is checked against.
+type EggOptions struct {
+	// Egg is the path of the insights-core egg run by insights-client.
+	Egg string
+	// Signature is the path of the detached armored signature of Egg.
+	Signature string
+	// Keyring is the path of the public keyring trusted to sign Egg.
+	Keyring string
+}
+
+// NewDefaultEggOptions reads the egg options from the EGG, EGG_SIGNATURE
+// and GPG_KEYRING environment variables, falling back to the locations
+// installed by the insights-client RPM.
+func NewDefaultEggOptions() *EggOptions {
+	opts := &EggOptions{
+		Egg:       os.Getenv("EGG"),
+		Signature: os.Getenv("EGG_SIGNATURE"),
+		Keyring:   os.Getenv("GPG_KEYRING"),
+	}
+	if len(opts.Egg) == 0 {
+		opts.Egg = DefaultEggLocation
+	}
+	if len(opts.Signature) == 0 {
+		opts.Signature = opts.Egg + ".asc"
+	}
+	if len(opts.Keyring) == 0 {
+		opts.Keyring = DefaultKeyringLocation
+	}
+	return opts
+}
+
+// VerifyEgg checks the egg against its detached signature using only the
+// configured keyring and returns the insights-core version it contains.
+func VerifyEgg(opts EggOptions) (string, error) {
+	for _, f := range []string{opts.Egg, opts.Signature, opts.Keyring} {
+		if _, err := os.Stat(f); err != nil {
+			return "", fmt.Errorf("Unable to verify egg: %v", err)
+		}
+	}
+
+	// Use a throwaway home so nothing but the configured keyring is trusted
+	home, err := ioutil.TempDir("", "insights-gpg")
+	if err != nil {
+		return "", fmt.Errorf("Unable to create gpg home: %v", err)
+	}
+	defer os.RemoveAll(home)
+
+	cmd := exec.Command(gpgCmd,
+		"--homedir", home,
+		"--no-default-keyring",
+		"--keyring", opts.Keyring,
+		"--verify", opts.Signature, opts.Egg)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return "", fmt.Errorf("Egg %s failed signature verification: %v: %s", opts.Egg, err, out)
+	}
+
+	return eggVersion(opts.Egg)
+}
+
+// eggVersion reads the insights-core VERSION and RELEASE files bundled in the egg
+func eggVersion(egg string) (string, error) {
+	r, err := zip.OpenReader(egg)
+	if err != nil {
+		return "", fmt.Errorf("Unable to open egg %s: %v", egg, err)
+	}
+	defer r.Close()
+
+	parts := make(map[string]string)
+	for _, f := range r.File {
+		if f.Name != "insights/VERSION" && f.Name != "insights/RELEASE" {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return "", err
+		}
+		content, err := ioutil.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			return "", err
+		}
+		parts[f.Name] = strings.TrimSpace(string(content))
+	}
+
+	version, ok := parts["insights/VERSION"]
+	if !ok {
+		return "", fmt.Errorf("Egg %s has no insights/VERSION", egg)
+	}
+	if release := parts["insights/RELEASE"]; len(release) > 0 {
+		version = version + "-" + release
+	}
+	return version, nil
+}
@@ -0,0 +1,222 @@
+package scanner
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+
+	"github.com/RedHatInsights/insights-ocp-controller/pkg/env"
+)
+
+// FailureKind classifies why a scan did not produce a report
+type FailureKind string
+
+const (
+	// FailureVerification means the egg did not match its signature
+	FailureVerification FailureKind = "EggVerificationFailed"
+	// FailureResourceLimit means the scanner exceeded one of its sandbox limits
+	FailureResourceLimit FailureKind = "ResourceLimitExceeded"
+	// FailureScanner means the scanner exited with an error or bad output
+	FailureScanner FailureKind = "ScannerFailed"
+)
+
+// ScanError is returned by scanners for a failed scan
+type ScanError struct {
+	Kind FailureKind
+	Err  error
+}
+
+func (e *ScanError) Error() string {
+	return fmt.Sprintf("%s: %v", e.Kind, e.Err)
+}
+
+// FailureKindOf returns the kind of a scan failure, FailureScanner for
+// errors that did not come from a scanner
+func FailureKindOf(err error) FailureKind {
+	if scanErr, ok := err.(*ScanError); ok {
+		return scanErr.Kind
+	}
+	return FailureScanner
+}
+
+// Paths hidden from the scanner so untrusted image content can never reach
+// the container runtime or the cluster with the controller's credentials
+var maskedPaths = []string{
+	"/var/run/docker.sock",
+	"/run/docker.sock",
+	"/var/run/secrets/kubernetes.io",
+	"/run/secrets/kubernetes.io",
+}
+
+// sandboxScript runs as root in a private mount namespace, masks the
+// sensitive paths, remounts the image content read only without setuid
+// binaries or devices, applies the rlimits and drops to the unprivileged
+// user. Dropping clears every capability set and forbids regaining
+// privileges, so nothing in the image can become root again and undo the
+// masks. All values come from the environment and the content path from $1.
+const sandboxScript = `set -e
+mount --make-rprivate /
+mount --bind "$1" "$1"
+mount -o remount,bind,ro,nosuid,nodev "$1"
+for p in $SANDBOX_MASK; do
+	if [ -d "$p" ]; then
+		mount -t tmpfs -o ro,size=4k tmpfs "$p"
+	elif [ -e "$p" ]; then
+		mount --bind /dev/null "$p"
+	fi
+done
+ulimit -t "$SANDBOX_CPU" -v "$SANDBOX_MEMORY" -n "$SANDBOX_FILES" -f "$SANDBOX_FSIZE"
+cd "$1"
+unset SANDBOX_MASK SANDBOX_CPU SANDBOX_MEMORY SANDBOX_FILES SANDBOX_FSIZE
+exec setpriv --reuid="$SANDBOX_UID" --regid="$SANDBOX_GID" --clear-groups \
+	--no-new-privs --inh-caps=-all --bounding-set=-all \
+	-- "$0" --analyze-mountpoint="$1"
+`
+
+// SandboxOptions holds the identity and limits of sandboxed scanner runs
+type SandboxOptions struct {
+	// UID and GID the scanner runs as
+	UID int
+	GID int
+	// CPUSeconds is the CPU time limit
+	CPUSeconds int
+	// MemoryMB is the address space limit
+	MemoryMB int
+	// OpenFiles is the open file descriptor limit
+	OpenFiles int
+	// OutputMB limits both the report size and any file the scanner writes
+	OutputMB int
+}
+
+// NewDefaultSandboxOptions reads the sandbox options from SCAN_UID, SCAN_GID,
+// SCAN_CPU_SECONDS, SCAN_MEMORY_MB, SCAN_OPEN_FILES and SCAN_OUTPUT_MB
+func NewDefaultSandboxOptions() *SandboxOptions {
+	return &SandboxOptions{
+		UID:        env.Int("SCAN_UID", 65534),
+		GID:        env.Int("SCAN_GID", 65534),
+		CPUSeconds: env.Int("SCAN_CPU_SECONDS", 900),
+		MemoryMB:   env.Int("SCAN_MEMORY_MB", 2048),
+		OpenFiles:  env.Int("SCAN_OPEN_FILES", 1024),
+		OutputMB:   env.Int("SCAN_OUTPUT_MB", 32),
+	}
+}
+
+// Sandbox runs scanner processes confined to the content they scan
+type Sandbox struct {
+	opts SandboxOptions
+}
+
+// NewSandbox creates a sandbox with the given options
+func NewSandbox(opts SandboxOptions) *Sandbox {
+	return &Sandbox{opts: opts}
+}
+
+// Run executes command against contentPath inside the sandbox with only the
+// given environment and returns its standard output
+func (s *Sandbox) Run(command string, contentPath string, env []string) ([]byte, error) {
+	home, err := ioutil.TempDir("", "insights-scan")
+	if err != nil {
+		return nil, &ScanError{FailureScanner, err}
+	}
+	defer os.RemoveAll(home)
+	if err = os.Chown(home, s.opts.UID, s.opts.GID); err != nil {
+		return nil, &ScanError{FailureScanner, err}
+	}
+
+	cmd := exec.Command("/bin/sh", "-c", sandboxScript, command, contentPath)
+	cmd.Env = append([]string{
+		"PATH=/usr/local/sbin:/usr/local/bin:/usr/sbin:/usr/bin:/sbin:/bin",
+		"HOME=" + home,
+		"TMPDIR=" + home,
+		"SANDBOX_MASK=" + strings.Join(maskedPaths, " "),
+		"SANDBOX_UID=" + strconv.Itoa(s.opts.UID),
+		"SANDBOX_GID=" + strconv.Itoa(s.opts.GID),
+		"SANDBOX_CPU=" + strconv.Itoa(s.opts.CPUSeconds),
+		"SANDBOX_MEMORY=" + strconv.Itoa(s.opts.MemoryMB*1024),
+		"SANDBOX_FILES=" + strconv.Itoa(s.opts.OpenFiles),
+		"SANDBOX_FSIZE=" + strconv.Itoa(s.opts.OutputMB*2048),
+	}, env...)
+	cmd.SysProcAttr = &syscall.SysProcAttr{
+		Setpgid:    true,
+		Cloneflags: syscall.CLONE_NEWNS,
+	}
+
+	stdout := &limitedBuffer{limit: s.opts.OutputMB * 1024 * 1024, cmd: cmd}
+	var stderr bytes.Buffer
+	cmd.Stdout = stdout
+	cmd.Stderr = &stderr
+
+	err = cmd.Run()
+	if stdout.exceeded() {
+		return nil, &ScanError{FailureResourceLimit,
+			fmt.Errorf("scanner output exceeded %d MB", s.opts.OutputMB)}
+	}
+	if err != nil {
+		if limit, ok := exceededLimit(cmd, stderr.String()); ok {
+			return nil, &ScanError{FailureResourceLimit, fmt.Errorf("scanner exceeded %s limit", limit)}
+		}
+		return nil, &ScanError{FailureScanner, fmt.Errorf("%v: %s", err, stderr.String())}
+	}
+	return stdout.buf.Bytes(), nil
+}
+
+// exceededLimit inspects how the scanner died for signs of an rlimit violation
+func exceededLimit(cmd *exec.Cmd, stderr string) (string, bool) {
+	if cmd.ProcessState != nil {
+		if status, ok := cmd.ProcessState.Sys().(syscall.WaitStatus); ok && status.Signaled() {
+			switch status.Signal() {
+			case syscall.SIGXCPU:
+				return "CPU time", true
+			case syscall.SIGKILL:
+				return "CPU time or memory", true
+			case syscall.SIGXFSZ:
+				return "file size", true
+			}
+		}
+	}
+	switch {
+	case strings.Contains(stderr, "MemoryError"), strings.Contains(stderr, "Cannot allocate memory"):
+		return "memory", true
+	case strings.Contains(stderr, "Too many open files"):
+		return "open files", true
+	}
+	return "", false
+}
+
+// limitedBuffer collects output up to limit bytes and kills the whole
+// process group of cmd as soon as more is written
+type limitedBuffer struct {
+	buf   bytes.Buffer
+	limit int
+	cmd   *exec.Cmd
+	over  bool
+	lock  sync.Mutex
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	if b.over {
+		return 0, fmt.Errorf("output limit exceeded")
+	}
+	if b.buf.Len()+len(p) > b.limit {
+		b.over = true
+		if b.cmd.Process != nil {
+			syscall.Kill(-b.cmd.Process.Pid, syscall.SIGKILL)
+		}
+		return 0, fmt.Errorf("output limit exceeded")
+	}
+	return b.buf.Write(p)
+}
+
+func (b *limitedBuffer) exceeded() bool {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	return b.over
+}
@@ -0,0 +1,66 @@
+package scanner
+
+import (
+	"encoding/json"
+	"fmt"
+
+	iclient "github.com/RedHatInsights/insights-goapi/client"
+	"github.com/RedHatInsights/insights-goapi/common"
+)
+
+const (
+	clientCmd = "insights-client"
+	// EggVersionKey is the report field holding the version of the verified egg
+	EggVersionKey = "egg_version"
+)
+
+// EggScanner runs insights-client against extracted image content in a
+// sandbox, but only after the egg it is about to run has passed signature
+// verification.
+type EggScanner struct {
+	opts    EggOptions
+	sandbox *Sandbox
+}
+
+// NewEggScanner creates a scanner for the egg described by opts
+func NewEggScanner(opts EggOptions, sandbox *Sandbox) *EggScanner {
+	return &EggScanner{opts: opts, sandbox: sandbox}
+}
+
+var _ iclient.Scanner = &EggScanner{}
+
+// Verify checks the egg signature and returns the egg version
+func (s *EggScanner) Verify() (string, error) {
+	return VerifyEgg(s.opts)
+}
+
+// ScanImage verifies the egg and scans contentPath with it. The returned
+// report carries the verified egg version under EggVersionKey.
+func (s *EggScanner) ScanImage(contentPath string, imageId string) (*common.ScanResponse, *[]byte, error) {
+	version, err := s.Verify()
+	if err != nil {
+		return nil, nil, &ScanError{FailureVerification, fmt.Errorf("Refusing to scan %s: %v", imageId, err)}
+	}
+
+	jsonResp, err := s.sandbox.Run(clientCmd, contentPath, []string{"EGG=" + s.opts.Egg})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var scanResp common.ScanResponse
+	if err = json.Unmarshal(jsonResp, &scanResp); err != nil {
+		return nil, nil, &ScanError{FailureScanner, err}
+	}
+
+	// Keep every field insights-client produced and add the egg version
+	var report map[string]interface{}
+	if err = json.Unmarshal(jsonResp, &report); err != nil {
+		return nil, nil, &ScanError{FailureScanner, err}
+	}
+	report[EggVersionKey] = version
+	out, err := json.Marshal(report)
+	if err != nil {
+		return nil, nil, &ScanError{FailureScanner, err}
+	}
+	return &scanResp, &out, nil
+}
@@ -0,0 +1,90 @@
+package scope
+
+import (
+	"fmt"
+
+	"github.com/ghodss/yaml"
+	"k8s.io/kubernetes/pkg/labels"
+
+	"github.com/RedHatInsights/insights-ocp-controller/pkg/match"
+)
+
+// Reasons an image is skipped
+const (
+	// ReasonNotIncluded means include rules exist and none matched
+	ReasonNotIncluded = "NotIncluded"
+	// ReasonExcluded means an exclude rule matched
+	ReasonExcluded = "Excluded"
+	// ReasonOptedOut means the namespace or image stream opted out
+	ReasonOptedOut = "OptedOut"
+)
+
+// Selector matches images by where they were pushed. Every criterion set
+// must match; a selector without criteria matches every image.
+type Selector struct {
+	match.Target
+	// NamespaceSelector is a label selector for the namespace
+	NamespaceSelector string `json:"namespaceSelector,omitempty"`
+
+	selector labels.Selector
+}
+
+// Rules decide which images are scanned. Without include rules every image
+// not excluded is scanned.
+type Rules struct {
+	Include []Selector `json:"include,omitempty"`
+	Exclude []Selector `json:"exclude,omitempty"`
+}
+
+// Parse reads scope rules from YAML or JSON
+func Parse(data []byte) (*Rules, error) {
+	var rules Rules
+	if err := yaml.Unmarshal(data, &rules); err != nil {
+		return nil, fmt.Errorf("Unable to parse scope rules: %v", err)
+	}
+	for _, selectors := range [][]Selector{rules.Include, rules.Exclude} {
+		for i := range selectors {
+			s := &selectors[i]
+			selector, err := labels.Parse(s.NamespaceSelector)
+			if err != nil {
+				return nil, fmt.Errorf("Invalid namespace selector %q: %v", s.NamespaceSelector, err)
+			}
+			s.selector = selector
+			if err := s.Validate(); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return &rules, nil
+}
+
+// Skip returns why an image pushed to namespace under repositories is not
+// scanned, or an empty reason when it is
+func (r *Rules) Skip(namespace string, namespaceLabels map[string]string, repositories []string) string {
+	if r == nil {
+		return ""
+	}
+	if len(r.Include) > 0 && !matchAny(r.Include, namespace, namespaceLabels, repositories) {
+		return ReasonNotIncluded
+	}
+	if matchAny(r.Exclude, namespace, namespaceLabels, repositories) {
+		return ReasonExcluded
+	}
+	return ""
+}
+
+func matchAny(selectors []Selector, namespace string, namespaceLabels map[string]string, repositories []string) bool {
+	for i := range selectors {
+		if selectors[i].matches(namespace, namespaceLabels, repositories) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Selector) matches(namespace string, namespaceLabels map[string]string, repositories []string) bool {
+	if s.selector != nil && !s.selector.Matches(labels.Set(namespaceLabels)) {
+		return false
+	}
+	return s.Matches(namespace, repositories)
+}
@@ -0,0 +1,43 @@
+package scope
+
+import (
+	"io/ioutil"
+	"testing"
+)
+
+func TestScopeRules(t *testing.T) {
+	data, err := ioutil.ReadFile("testdata/scope.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rules, err := Parse(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	production := map[string]string{"env": "production"}
+
+	cases := []struct {
+		namespace  string
+		labels     map[string]string
+		repository string
+		reason     string
+	}{
+		{"app", production, "app/web", ""},
+		{"shared", nil, "shared/base", ""},
+		{"dev", map[string]string{"env": "dev"}, "dev/web", ReasonNotIncluded},
+		{"secrets", production, "secrets/vault", ReasonExcluded},
+		{"app", production, "app/debug-tools", ReasonExcluded},
+	}
+	for _, c := range cases {
+		if reason := rules.Skip(c.namespace, c.labels, []string{c.repository}); reason != c.reason {
+			t.Errorf("Expected %q for %s in %s, got %q", c.reason, c.repository, c.namespace, reason)
+		}
+	}
+}
+
+func TestNoScopeRules(t *testing.T) {
+	var rules *Rules
+	if reason := rules.Skip("any", nil, nil); reason != "" {
+		t.Fatalf("Expected every image scanned without rules, got %q", reason)
+	}
+}
@@ -0,0 +1,93 @@
+package suppress
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/ghodss/yaml"
+
+	"github.com/RedHatInsights/insights-goapi/common"
+
+	"github.com/RedHatInsights/insights-ocp-controller/pkg/match"
+)
+
+// Suppression accepts the risk of a rule. Without namespaces or repositories
+// it applies to every image; without an expiry date it never expires.
+type Suppression struct {
+	Rule string `json:"rule"`
+	// Target limits the images the suppression applies to
+	match.Target
+	Expires *time.Time `json:"expires,omitempty"`
+	Reason  string     `json:"reason,omitempty"`
+}
+
+// Suppressions is the list of local suppression rules
+type Suppressions struct {
+	Suppressions []Suppression `json:"suppressions"`
+}
+
+// Parse reads suppressions from YAML or JSON
+func Parse(data []byte) (*Suppressions, error) {
+	var suppressions Suppressions
+	if err := yaml.Unmarshal(data, &suppressions); err != nil {
+		return nil, fmt.Errorf("Unable to parse suppressions: %v", err)
+	}
+	for i, s := range suppressions.Suppressions {
+		if len(s.Rule) == 0 {
+			return nil, fmt.Errorf("Suppression %d has no rule", i)
+		}
+		if err := s.Validate(); err != nil {
+			return nil, fmt.Errorf("Suppression of %s: %v", s.Rule, err)
+		}
+	}
+	return &suppressions, nil
+}
+
+// Active returns the suppressions that have not expired at now
+func (ss *Suppressions) Active(now time.Time) *Suppressions {
+	if ss == nil {
+		return nil
+	}
+	active := &Suppressions{Suppressions: []Suppression{}}
+	for _, s := range ss.Suppressions {
+		if s.Expires == nil || now.Before(*s.Expires) {
+			active.Suppressions = append(active.Suppressions, s)
+		}
+	}
+	return active
+}
+
+// Apply splits reports into the active findings and the suppressed ones for
+// an image in namespace known under repositories. Findings acknowledged in
+// Insights are always suppressed.
+func (ss *Suppressions) Apply(reports map[string]common.Report, namespace string, repositories []string, now time.Time) (map[string]common.Report, map[string]common.Report) {
+	active := make(map[string]common.Report, len(reports))
+	suppressed := make(map[string]common.Report)
+	for rule, report := range reports {
+		if len(report.Acks) > 0 || ss.suppresses(rule, namespace, repositories, now) {
+			suppressed[rule] = report
+		} else {
+			active[rule] = report
+		}
+	}
+	return active, suppressed
+}
+
+func (ss *Suppressions) suppresses(rule string, namespace string, repositories []string, now time.Time) bool {
+	if ss == nil {
+		return false
+	}
+	for _, s := range ss.Suppressions {
+		if s.Rule == rule && s.appliesTo(namespace, repositories, now) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Suppression) appliesTo(namespace string, repositories []string, now time.Time) bool {
+	if s.Expires != nil && !now.Before(*s.Expires) {
+		return false
+	}
+	return s.Matches(namespace, repositories)
+}
@@ -0,0 +1,87 @@
+package suppress
+
+import (
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/RedHatInsights/insights-goapi/common"
+)
+
+const (
+	everywhere = "CVE_2017_5715_CPU_VIRT|VIRT_CVE_2017_5715_CPU_3_ONLYKERNEL"
+	scoped     = "CVE_2016_5195_KERNEL|KERNEL_CVE_2016_5195_2"
+	acked      = "HARDENING_SSH|SSH_ROOT_LOGIN"
+)
+
+func loadSuppressions(t *testing.T) *Suppressions {
+	data, err := ioutil.ReadFile("testdata/suppressions.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	suppressions, err := Parse(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return suppressions
+}
+
+func testReports() map[string]common.Report {
+	return map[string]common.Report{
+		everywhere: {Severity: "WARN"},
+		scoped:     {Severity: "ERROR"},
+		acked:      {Severity: "INFO", Acks: []interface{}{map[string]interface{}{"id": 1}}},
+	}
+}
+
+func TestSuppressionScope(t *testing.T) {
+	suppressions := loadSuppressions(t)
+	now := time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	active, suppressed := suppressions.Apply(testReports(), "legacy", []string{"legacy/app"}, now)
+	if len(active) != 0 || len(suppressed) != 3 {
+		t.Fatalf("Expected every finding suppressed, got active %v", active)
+	}
+
+	active, suppressed = suppressions.Apply(testReports(), "legacy", []string{"other/app"}, now)
+	if _, ok := active[scoped]; !ok || len(suppressed) != 2 {
+		t.Fatalf("Expected the scoped rule to stay active for another repository, got active %v", active)
+	}
+
+	active, _ = suppressions.Apply(testReports(), "prod", []string{"legacy/app"}, now)
+	if _, ok := active[scoped]; !ok {
+		t.Fatalf("Expected the scoped rule to stay active in another namespace, got active %v", active)
+	}
+}
+
+func TestSuppressionExpiry(t *testing.T) {
+	suppressions := loadSuppressions(t)
+	later := time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC)
+	active, _ := suppressions.Apply(testReports(), "legacy", []string{"legacy/app"}, later)
+	if _, ok := active[scoped]; !ok || len(active) != 1 {
+		t.Fatalf("Expected only the expired suppression to be active, got %v", active)
+	}
+}
+
+func TestActiveDropsExpired(t *testing.T) {
+	suppressions := loadSuppressions(t)
+	before := suppressions.Active(time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC))
+	if len(before.Suppressions) != 2 {
+		t.Fatalf("Expected both suppressions active before the expiry, got %v", before.Suppressions)
+	}
+	after := suppressions.Active(time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC))
+	if len(after.Suppressions) != 1 || after.Suppressions[0].Rule != everywhere {
+		t.Fatalf("Expected only the unexpiring suppression active, got %v", after.Suppressions)
+	}
+	if (*Suppressions)(nil).Active(time.Now()) != nil {
+		t.Fatal("Expected no suppressions to stay none")
+	}
+}
+
+func TestAcksWithoutSuppressions(t *testing.T) {
+	var suppressions *Suppressions
+	active, suppressed := suppressions.Apply(testReports(), "", nil, time.Now())
+	if _, ok := suppressed[acked]; !ok || len(active) != 2 {
+		t.Fatalf("Expected acknowledged finding suppressed, got %v", suppressed)
+	}
+}